@@ -0,0 +1,242 @@
+package validate
+
+import (
+	"fmt"
+	"math"
+	"mime/multipart"
+	"reflect"
+	"strings"
+)
+
+// WithInto validates like With(...) and then binds the coerced params into
+// dst, which must be a pointer to a struct. Binding errors are reported
+// alongside validation errors.
+func (v *ValidationData) WithInto(rules RuleBook, dst interface{}) map[string][]error {
+	params, errors := v.With(rules)
+	for k, errs := range Bind(params, dst) {
+		for _, err := range errs {
+			ve := err.(*ValidationError)
+			ve.Message = v.message(nil, ve)
+			errors[k] = append(errors[k], ve)
+		}
+	}
+
+	return errors
+}
+
+// Bind copies params into the fields of dst, matched by json tag or field
+// name. Numbers are converted between kinds only when the value fits, and
+// nested maps and slices are bound into nested structs and slices. Any param
+// that can't be assigned is reported under its key.
+func Bind(params map[string]interface{}, dst interface{}) map[string][]error {
+	val := reflect.ValueOf(dst)
+	if val.Kind() != reflect.Ptr || val.IsNil() || val.Elem().Kind() != reflect.Struct {
+		panic("Bind(...) expects a pointer to a struct. Got: " + reflect.TypeOf(dst).String())
+	}
+
+	errors := make(map[string][]error)
+	bindStruct(val.Elem(), params, "", errors)
+	return errors
+}
+
+func bindStruct(dst reflect.Value, params map[string]interface{}, path string, errors map[string][]error) {
+	for _, field := range fieldsOf(dst.Type()) {
+		param, ok := lookupKey(params, field.key)
+		if !ok {
+			continue
+		}
+
+		key := joinPath(path, field.key)
+		if err := assign(settableField(dst, field.index), param, key, errors); err != nil {
+			Log.Debug("Could not bind %v: %v", key, err)
+			addError(errors, key, err)
+		}
+	}
+}
+
+// assign sets dst to val, converting where it's safe to do so
+func assign(dst reflect.Value, val interface{}, path string, errors map[string][]error) error {
+	if val == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	src := reflect.ValueOf(val)
+	if src.Type().AssignableTo(dst.Type()) {
+		dst.Set(src)
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(dst.Type().Elem())
+		if files, ok := val.([]*multipart.FileHeader); ok && dst.Type() == fileType {
+			if len(files) != 1 {
+				return NewError(CodeMaxFiles, len(files), map[string]interface{}{"max": 1},
+					"Can't bind %v files to a single file", len(files))
+			}
+			dst.Set(reflect.ValueOf(files[0]))
+			return nil
+		}
+		if err := assign(elem.Elem(), val, path, errors); err != nil {
+			return err
+		}
+		dst.Set(elem)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return assignInt(dst, src)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return assignUint(dst, src)
+	case reflect.Float32, reflect.Float64:
+		return assignFloat(dst, src)
+	case reflect.String:
+		if src.Kind() == reflect.String {
+			dst.SetString(src.String())
+			return nil
+		}
+	case reflect.Bool:
+		if src.Kind() == reflect.Bool {
+			dst.SetBool(src.Bool())
+			return nil
+		}
+	case reflect.Struct:
+		if nested, ok := toMap(val); ok {
+			bindStruct(dst, nested, path, errors)
+			return nil
+		}
+	case reflect.Slice:
+		vals, ok := toSlice(val)
+		if !ok {
+			break
+		}
+		slice := reflect.MakeSlice(dst.Type(), len(vals), len(vals))
+		for i, elem := range vals {
+			elemPath := fmt.Sprintf("%v[%v]", path, i)
+			if err := assign(slice.Index(i), elem, elemPath, errors); err != nil {
+				addError(errors, elemPath, err)
+			}
+		}
+		dst.Set(slice)
+		return nil
+	}
+
+	return bindError(src, dst)
+}
+
+func assignInt(dst reflect.Value, src reflect.Value) error {
+	var i int64
+	switch src.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i = src.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if src.Uint() > math.MaxInt64 {
+			return overflowError(src.Interface(), dst.Type().String())
+		}
+		i = int64(src.Uint())
+	case reflect.Float32, reflect.Float64:
+		f := src.Float()
+		if f != math.Trunc(f) {
+			return integerError(f)
+		}
+		if f < math.MinInt64 || f >= math.MaxInt64 {
+			return overflowError(f, dst.Type().String())
+		}
+		i = int64(f)
+	default:
+		return bindError(src, dst)
+	}
+
+	if dst.OverflowInt(i) {
+		return overflowError(i, dst.Type().String())
+	}
+	dst.SetInt(i)
+	return nil
+}
+
+func assignUint(dst reflect.Value, src reflect.Value) error {
+	var u uint64
+	switch src.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if src.Int() < 0 {
+			return overflowError(src.Int(), dst.Type().String())
+		}
+		u = uint64(src.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u = src.Uint()
+	case reflect.Float32, reflect.Float64:
+		f := src.Float()
+		if f != math.Trunc(f) {
+			return integerError(f)
+		}
+		if f < 0 || f >= math.MaxUint64 {
+			return overflowError(f, dst.Type().String())
+		}
+		u = uint64(f)
+	default:
+		return bindError(src, dst)
+	}
+
+	if dst.OverflowUint(u) {
+		return overflowError(u, dst.Type().String())
+	}
+	dst.SetUint(u)
+	return nil
+}
+
+func assignFloat(dst reflect.Value, src reflect.Value) error {
+	var f float64
+	switch src.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f = float64(src.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		f = float64(src.Uint())
+	case reflect.Float32, reflect.Float64:
+		f = src.Float()
+	default:
+		return bindError(src, dst)
+	}
+
+	if dst.OverflowFloat(f) {
+		return overflowError(f, dst.Type().String())
+	}
+	dst.SetFloat(f)
+	return nil
+}
+
+/* * * * * * * * * * * * *
+  Helper Functions
+* * * * * * * * * * * * */
+
+func bindError(src reflect.Value, dst reflect.Value) error {
+	return NewError(CodeType, src.Interface(), map[string]interface{}{"expected": dst.Type().String()},
+		"Can't bind %v to a field of type %v", src.Type(), dst.Type())
+}
+
+// lookupKey finds key in params, falling back to a case-insensitive match
+// like encoding/json does
+func lookupKey(params map[string]interface{}, key string) (interface{}, bool) {
+	if val, ok := params[key]; ok {
+		return val, true
+	}
+	for k, val := range params {
+		if strings.EqualFold(k, key) {
+			return val, true
+		}
+	}
+	return nil, false
+}
+
+// settableField walks to a (possibly embedded) field, allocating any nil
+// embedded pointers on the way
+func settableField(val reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && val.Kind() == reflect.Ptr {
+			if val.IsNil() {
+				val.Set(reflect.New(val.Type().Elem()))
+			}
+			val = val.Elem()
+		}
+		val = val.Field(x)
+	}
+	return val
+}
@@ -0,0 +1,82 @@
+package validate_test
+
+import (
+	. "github.com/franela/goblin"
+	. "github.com/joslinm/validate"
+	"testing"
+	"time"
+)
+
+type profile struct {
+	Name    string    `json:"name"`
+	Age     int8      `json:"age"`
+	Score   float32   `json:"score"`
+	Admin   *bool     `json:"admin"`
+	Joined  time.Time `json:"joined"`
+	Tags    []string  `json:"tags"`
+	Visits  []uint    `json:"visits"`
+	Address address   `json:"address"`
+	Limit   int
+}
+
+func TestBind(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Bind", func() {
+		g.It("Should bind validated params into a struct", func() {
+			var p profile
+			errors := Validate(map[string]interface{}{
+				"name":    "ann",
+				"age":     "42",
+				"score":   "9.5",
+				"admin":   "true",
+				"joined":  time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC),
+				"tags":    []interface{}{"a", "b"},
+				"visits":  []interface{}{"1", 2},
+				"address": map[string]interface{}{"city": "Boston"},
+			}).WithInto(RuleBook{
+				"name":    RB.String(),
+				"age":     RB.Number(),
+				"score":   RB.Number(),
+				"admin":   RB.Bool(),
+				"joined":  RB.Time(),
+				"tags":    RB.Each(RB.String()),
+				"visits":  RB.Each(RB.Number()),
+				"address": RuleBook{"city": RB.String()},
+			}, &p)
+			g.Assert(len(errors)).Equal(0)
+			g.Assert(p.Name).Equal("ann")
+			g.Assert(p.Age).Equal(int8(42))
+			g.Assert(p.Score).Equal(float32(9.5))
+			g.Assert(*p.Admin).IsTrue()
+			g.Assert(p.Joined.Year()).Equal(2015)
+			g.Assert(p.Tags).Equal([]string{"a", "b"})
+			g.Assert(p.Visits).Equal([]uint{1, 2})
+			g.Assert(p.Address.City).Equal("Boston")
+		})
+		g.It("Should match field names case-insensitively when untagged", func() {
+			var p profile
+			errors := Bind(map[string]interface{}{"limit": 10}, &p)
+			g.Assert(len(errors)).Equal(0)
+			g.Assert(p.Limit).Equal(10)
+		})
+		g.It("Should bind into a struct that embeds itself", func() {
+			var c chain
+			errors := Bind(map[string]interface{}{"name": "a"}, &c)
+			g.Assert(len(errors)).Equal(0)
+			g.Assert(c.Name).Equal("a")
+		})
+		g.It("Should report values that don't fit their field", func() {
+			var p profile
+			errors := Bind(map[string]interface{}{
+				"age":    300,
+				"Limit":  1.5,
+				"name":   7,
+				"visits": []interface{}{-1},
+			}, &p)
+			g.Assert(len(errors["age"])).Equal(1)
+			g.Assert(len(errors["Limit"])).Equal(1)
+			g.Assert(len(errors["name"])).Equal(1)
+			g.Assert(len(errors["visits[0]"])).Equal(1)
+		})
+	})
+}
@@ -0,0 +1,182 @@
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+)
+
+// coerceDecimal converts an input to the exact *big.Rat it stands for.
+// Numeric strings and json.Number are parsed exactly; a float is taken to
+// mean its shortest decimal form, so 0.1 is 1/10 rather than the binary
+// fraction closest to it.
+func (rule *Rule) coerceDecimal(input interface{}) (interface{}, error) {
+	if r, ok := toRat(input); ok {
+		return r, nil
+	}
+	return input, ConversionError(input, typeName(rule.Type))
+}
+
+func (rule *Rule) evalDecimal(val *big.Rat) (bool, []error) {
+	var errors []error
+
+	if min := rule.minDecimal(); min != nil && val.Cmp(min) < 0 {
+		errors = append(errors, NewError(CodeMin, decimalString(val), map[string]interface{}{"min": decimalString(min)},
+			"Input(%v) < Minimum(%v)", decimalString(val), decimalString(min)))
+	}
+	if max := rule.maxDecimal(); max != nil && val.Cmp(max) > 0 {
+		errors = append(errors, NewError(CodeMax, decimalString(val), map[string]interface{}{"max": decimalString(max)},
+			"Input(%v) > Maximum(%v)", decimalString(val), decimalString(max)))
+	}
+
+	scale, exact := decimalScale(val)
+	if rule.DidSetScale && (!exact || scale > rule.Scale) {
+		errors = append(errors, NewError(CodeScale, decimalString(val), map[string]interface{}{"scale": rule.Scale},
+			"[%v] has more than %v decimal places", decimalString(val), rule.Scale))
+	}
+	if rule.DidSetScale && scale < rule.Scale {
+		scale = rule.Scale // digits are counted as if padded to the scale
+	}
+	if rule.Precision > 0 && (!exact || decimalDigits(val, scale) > rule.Precision) {
+		errors = append(errors, NewError(CodePrecision, decimalString(val), map[string]interface{}{"precision": rule.Precision},
+			"[%v] has more than %v digits", decimalString(val), rule.Precision))
+	}
+
+	return len(errors) == 0, errors
+}
+
+// minDecimal prefers MinDecimal to a float Min
+func (rule *Rule) minDecimal() *big.Rat {
+	if rule.MinDecimal != nil {
+		return rule.MinDecimal
+	} else if rule.DidSetMin {
+		r, _ := toRat(rule.Min)
+		return r
+	}
+	return nil
+}
+
+func (rule *Rule) maxDecimal() *big.Rat {
+	if rule.MaxDecimal != nil {
+		return rule.MaxDecimal
+	} else if rule.DidSetMax {
+		r, _ := toRat(rule.Max)
+		return r
+	}
+	return nil
+}
+
+/* * * * * * * * * * * * *
+  Helper Functions
+* * * * * * * * * * * * */
+
+// MaxDecimalLength caps the characters of a decimal literal. Parsing gets
+// quadratically slower with the number of digits, so a longer one is refused
+// rather than parsed.
+var MaxDecimalLength = 1000
+
+// plain decimal notation; big.Rat would also take fractions, hex and
+// exponents large enough to take a while to expand
+var decimalPattern = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d{1,3})?$`)
+
+func toRat(input interface{}) (*big.Rat, bool) {
+	switch val := input.(type) {
+	case *big.Rat:
+		if val == nil {
+			return nil, false
+		}
+		return new(big.Rat).Set(val), true
+	case *big.Int:
+		if val == nil {
+			return nil, false
+		}
+		return new(big.Rat).SetInt(val), true
+	case *big.Float:
+		if val == nil || val.IsInf() {
+			return nil, false
+		}
+		r, _ := val.Rat(nil)
+		return r, true
+	case json.Number:
+		return toRat(string(val))
+	case string:
+		if len(val) > MaxDecimalLength || !decimalPattern.MatchString(val) {
+			return nil, false
+		}
+		return new(big.Rat).SetString(val)
+	}
+
+	v := reflect.ValueOf(input)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return new(big.Rat).SetInt64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return new(big.Rat).SetInt(new(big.Int).SetUint64(v.Uint())), true
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, false
+		}
+		return new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, v.Type().Bits()))
+	}
+	return nil, false
+}
+
+// decimalScale counts the digits after the decimal point. A fraction like
+// 1/3 has no exact decimal form.
+func decimalScale(r *big.Rat) (int, bool) {
+	denom := new(big.Int).Set(r.Denom())
+	two, five, zero := big.NewInt(2), big.NewInt(5), new(big.Int)
+	mod := new(big.Int)
+
+	twos, fives := 0, 0
+	for mod.Mod(denom, two).Cmp(zero) == 0 {
+		denom.Quo(denom, two)
+		twos++
+	}
+	for mod.Mod(denom, five).Cmp(zero) == 0 {
+		denom.Quo(denom, five)
+		fives++
+	}
+	if denom.Cmp(big.NewInt(1)) != 0 {
+		return 0, false
+	}
+	if twos > fives {
+		return twos, true
+	}
+	return fives, true
+}
+
+// decimalDigits counts the digits of a value with the given scale, ignoring
+// leading zeros before the decimal point
+func decimalDigits(r *big.Rat, scale int) int {
+	scaled := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)
+	scaled.Mul(scaled, r.Num())
+	scaled.Quo(scaled, r.Denom())
+	digits := len(scaled.Abs(scaled).String())
+	if digits < scale {
+		return scale
+	}
+	return digits
+}
+
+// decimalString prints the exact decimal form, or a fraction if there's none
+func decimalString(r *big.Rat) string {
+	if scale, exact := decimalScale(r); exact {
+		return r.FloatString(scale)
+	}
+	return r.RatString()
+}
+
+// parseDecimal is for builders, which panic on a bad literal
+func parseDecimal(literal string) *big.Rat {
+	r, ok := new(big.Rat).SetString(literal)
+	if !ok {
+		panic(fmt.Sprintf("%q is not a decimal", literal))
+	}
+	return r
+}
@@ -0,0 +1,117 @@
+package validate
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Document is the complete input being validated, handed to every
+// CheckCallback so a rule can look beyond its own value
+type Document struct {
+	// the raw (uncoerced) input; for a request, its decoded parameters
+	Data map[string]interface{}
+
+	// the request being validated, if any
+	Request *http.Request
+
+	// what Data is being validated with, for Value(...)
+	rules    RuleBook
+	settings *ValidationData
+	now      time.Time
+}
+
+// Get looks up a raw value by path, e.g. "date.start" or "tags[3]"
+func (doc *Document) Get(path string) (interface{}, bool) {
+	var current interface{} = doc.Data
+	for _, segment := range splitPath(path) {
+		if vals, ok := toSlice(current); ok {
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(vals) {
+				return nil, false
+			}
+			current = vals[index]
+			continue
+		}
+
+		m, ok := toMap(current)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[segment]; !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// Value looks up a value by path like Get(...), but coerced and checked by
+// its rule, e.g. a time.Time for a Time() rule given a string. A missing
+// value takes its rule's Default. It's false when there's no value, no rule
+// for the path or the value fails it. The rule's own Checks and other-field
+// comparisons aren't run.
+func (doc *Document) Value(path string) (interface{}, bool) {
+	var rules interface{} = doc.rules
+	var current interface{} = doc.Data
+	for _, segment := range splitPath(path) {
+		switch r := rules.(type) {
+		case RuleBook:
+			m, ok := toMap(current)
+			if !ok {
+				return nil, false
+			}
+			rb, isRule := r[segment].(ruleBuilder)
+			if !isRule {
+				rules, current = r[segment], m[segment]
+				continue
+			}
+			rule := rb.Build()
+			rules = &rule
+			if current, ok = lookup(m, rule.names(segment)); !ok {
+				if rule.Default == nil {
+					return nil, false
+				}
+				current = rule.Default
+			}
+		case *Rule:
+			vals, ok := toSlice(current)
+			index, err := strconv.Atoi(segment)
+			if !ok || err != nil || index < 0 || index >= len(vals) {
+				return nil, false
+			}
+			rules, current = r.Element, vals[index]
+		default:
+			return nil, false
+		}
+	}
+
+	rule, ok := rules.(*Rule)
+	if !ok || rule == nil || doc.settings == nil {
+		return nil, false
+	}
+	standalone := *rule
+	standalone.Checks, standalone.Fields = nil, nil
+	s := doc.settings.session(doc)
+	s.now = doc.now
+	return s.process(&standalone, current, path)
+}
+
+/* * * * * * * * * * * * *
+  Helper Functions
+* * * * * * * * * * * * */
+
+// splitPath breaks "a.b[2].c" into "a", "b", "2", "c"
+func splitPath(path string) []string {
+	path = strings.Replace(path, "[", ".", -1)
+	path = strings.Replace(path, "]", "", -1)
+
+	var segments []string
+	for _, segment := range strings.Split(path, ".") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
@@ -1,12 +1,149 @@
 package validate
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 )
 
+// Error codes
+const (
+	CodeType     = "type"
+	CodeRequired = "required"
+	CodeNull     = "null"
+	CodeMin      = "min"
+	CodeMax      = "max"
+	CodeRegex    = "regex"
+	CodeIn       = "in"
+	CodeBefore   = "before"
+	CodeAfter    = "after"
+	CodeLayout   = "layout"
+	CodeEpoch    = "epoch"
+	CodeCustom   = "custom"
+	CodeUnknown  = "unknown"
+	CodeOverflow = "overflow"
+	CodeInteger  = "integer"
+
+	// relative times
+	CodePast       = "past"
+	CodeFuture     = "future"
+	CodeWithinLast = "within_last"
+	CodeWithinNext = "within_next"
+	CodeMinAge     = "min_age"
+
+	// decimals
+	CodePrecision = "precision"
+	CodeScale     = "scale"
+
+	// other fields
+	CodeEqualsField         = "equals_field"
+	CodeGreaterThanField    = "gt_field"
+	CodeGreaterOrEqualField = "gte_field"
+	CodeAfterField          = "after_field"
+	CodeBeforeField         = "before_field"
+
+	// slices
+	CodeMinItems = "min_items"
+	CodeMaxItems = "max_items"
+	CodeUnique   = "unique"
+
+	// files
+	CodeMaxFiles    = "max_files"
+	CodeMaxSize     = "max_size"
+	CodeContentType = "content_type"
+	CodeExtension   = "extension"
+	CodeFile        = "file"
+
+	// requests
+	CodeMalformed    = "malformed"
+	CodeBodyTooLarge = "body_too_large"
+	CodeBodyTooDeep  = "body_too_deep"
+)
+
+// ValidationError describes a single failure. Field is the path of the value
+// that failed (e.g. "date.start" or "tags[3]"), Code says which check failed
+// and Params holds the limits it was checked against.
+type ValidationError struct {
+	Field   string                 `json:"field"`
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Params  map[string]interface{} `json:"params"`
+	Value   interface{}            `json:"value"`
+
+	// underlying cause, e.g. the error returned by a Check
+	Err error `json:"-"`
+}
+
+// NewError creates a ValidationError; its Field is filled in when it's
+// recorded against a path
+func NewError(code string, value interface{}, params map[string]interface{}, format string, args ...interface{}) *ValidationError {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	return &ValidationError{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+		Params:  params,
+		Value:   value,
+	}
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// MarshalJSON always emits the same keys; a value that can't be marshaled is
+// emitted as its string form
+func (e *ValidationError) MarshalJSON() ([]byte, error) {
+	type plain ValidationError
+	out := plain(*e)
+	if _, err := json.Marshal(out.Value); err != nil {
+		out.Value = fmt.Sprint(out.Value)
+	}
+	if out.Params == nil {
+		out.Params = map[string]interface{}{}
+	}
+	return json.Marshal(out)
+}
+
 func ConversionError(got interface{}, expected interface{}) error {
-	return fmt.Errorf(`
-    Got: %v
-    Expecting: %v"`, reflect.TypeOf(got).Kind().String(), expected)
+	return NewError(CodeType, got, map[string]interface{}{"expected": expected},
+		"Bad input type. Expecting type %v. Got: %v", expected, reflect.TypeOf(got))
+}
+
+func overflowError(val interface{}, t string) error {
+	return NewError(CodeOverflow, val, map[string]interface{}{"type": t}, "%v overflows %v", val, t)
+}
+
+func integerError(val interface{}) error {
+	return NewError(CodeInteger, val, nil, "%v is not a whole number", val)
+}
+
+/* * * * * * * * * * * * *
+  Helper Functions
+* * * * * * * * * * * * */
+
+// addError records err under path and returns what was recorded. Errors
+// that aren't ValidationErrors (e.g. from a Check) are wrapped as CodeCustom.
+func addError(errs map[string][]error, path string, err error) *ValidationError {
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		recorded := *ve
+		if recorded.Field == "" {
+			recorded.Field = path
+		}
+		ve = &recorded
+	} else {
+		ve = NewError(CodeCustom, nil, nil, "%v", err)
+		ve.Field = path
+		ve.Err = err
+	}
+
+	errs[path] = append(errs[path], ve)
+	return ve
 }
@@ -0,0 +1,69 @@
+package validate_test
+
+import (
+	"encoding/json"
+	"errors"
+	. "github.com/franela/goblin"
+	. "github.com/joslinm/validate"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("ValidationError", func() {
+		firstError := func(errs map[string][]error, key string) *ValidationError {
+			var ve *ValidationError
+			g.Assert(errors.As(errs[key][0], &ve)).IsTrue()
+			return ve
+		}
+
+		g.It("Should carry the field, code, params and value", func() {
+			_, errs := Validate(map[string]interface{}{
+				"age":  "12",
+				"plan": "gold",
+			}).With(RuleBook{
+				"age":   RB.Min(18),
+				"plan":  RB.In([]string{"free", "pro"}),
+				"email": RB.Required().Email(),
+			})
+
+			age := firstError(errs, "age")
+			g.Assert(age.Field).Equal("age")
+			g.Assert(age.Code).Equal(CodeMin)
+			g.Assert(age.Params["min"]).Equal(float64(18))
+			g.Assert(age.Value).Equal(float64(12))
+
+			plan := firstError(errs, "plan")
+			g.Assert(plan.Code).Equal(CodeIn)
+			g.Assert(plan.Params["allowed"]).Equal([]string{"free", "pro"})
+
+			g.Assert(firstError(errs, "email").Code).Equal(CodeRequired)
+		})
+		g.It("Should use element paths as the field", func() {
+			_, errs := Validate(map[string]interface{}{
+				"tags": []interface{}{"a", 1},
+			}).With(RuleBook{"tags": RB.Each(RB.String())})
+			ve := firstError(errs, "tags[1]")
+			g.Assert(ve.Field).Equal("tags[1]")
+			g.Assert(ve.Code).Equal(CodeType)
+			g.Assert(ve.Params["expected"]).Equal("string")
+		})
+		g.It("Should wrap errors returned by a Check", func() {
+			cause := errors.New("nope")
+			_, errs := Validate(map[string]interface{}{"x": "a"}).With(RuleBook{
+				"x": RB.String().Check(func(val interface{}, path string, doc *Document) error {
+					return cause
+				}),
+			})
+			ve := firstError(errs, "x")
+			g.Assert(ve.Code).Equal(CodeCustom)
+			g.Assert(errors.Is(errs["x"][0], cause)).IsTrue()
+		})
+		g.It("Should marshal to stable JSON", func() {
+			_, errs := Validate(map[string]interface{}{"x": 11}).With(RuleBook{"x": RB.Max(10)})
+			out, err := json.Marshal(errs["x"][0])
+			g.Assert(err == nil).IsTrue()
+			g.Assert(string(out)).Equal(`{"field":"x","code":"max","message":"x must be at most 10","params":{"max":10},"value":11}`)
+		})
+	})
+}
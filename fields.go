@@ -0,0 +1,153 @@
+package validate
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"time"
+)
+
+// FieldComparison compares a value against another field at the same level
+// of the RuleBook, e.g. "end" after "start". Code is the comparison to make
+// (CodeEqualsField, CodeGreaterThanField, ...) and doubles as the error code.
+type FieldComparison struct {
+	Field string
+	Code  string
+}
+
+// compare checks a value against the already validated params of its
+// siblings. A sibling that is missing or failed its own rule isn't compared
+// against; its own errors say what's wrong.
+func (s *validation) compare(cmp FieldComparison, val interface{}) error {
+	other, ok := s.siblings[cmp.Field]
+	if !ok || other == nil {
+		return nil
+	}
+
+	params := map[string]interface{}{"other": cmp.Field}
+	switch cmp.Code {
+	case CodeEqualsField:
+		if !equalValues(val, other) {
+			return NewError(cmp.Code, val, params, "[%v] doesn't equal %v", val, cmp.Field)
+		}
+	case CodeGreaterThanField:
+		if order, ok := compareValues(val, other); !ok || order <= 0 {
+			return NewError(cmp.Code, val, params, "[%v] isn't greater than %v", val, cmp.Field)
+		}
+	case CodeGreaterOrEqualField:
+		if order, ok := compareValues(val, other); !ok || order < 0 {
+			return NewError(cmp.Code, val, params, "[%v] is less than %v", val, cmp.Field)
+		}
+	case CodeAfterField:
+		if order, ok := compareValues(val, other); !ok || order <= 0 {
+			return NewError(cmp.Code, val, params, "[%v] isn't after %v", val, cmp.Field)
+		}
+	case CodeBeforeField:
+		if order, ok := compareValues(val, other); !ok || order >= 0 {
+			return NewError(cmp.Code, val, params, "[%v] isn't before %v", val, cmp.Field)
+		}
+	}
+	return nil
+}
+
+/* * * * * * * * * * * * *
+  Helper Functions
+* * * * * * * * * * * * */
+
+// dependsOnAny reports whether rule k compares itself against any of keys
+// other than itself
+func dependsOnAny(rb ruleBuilder, keys []string, k string) bool {
+	for _, cmp := range rb.Build().Fields {
+		for _, key := range keys {
+			if cmp.Field == key && key != k {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matches reports whether a raw sibling is present and, if any values are
+// given, equal to one of them. Values are compared by their string form too
+// since request parameters are always strings.
+func matches(siblings map[string]interface{}, field string, values []interface{}) bool {
+	input, ok := siblings[field]
+	if !ok || input == nil {
+		return false
+	}
+	if len(values) == 0 {
+		return true
+	}
+	for _, value := range values {
+		if equalValues(input, value) || fmt.Sprint(input) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func equalValues(a interface{}, b interface{}) bool {
+	if order, ok := compareValues(a, b); ok {
+		return order == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compareValues orders two times or two numbers (of any kind, exactly when
+// either is a decimal); anything else can't be ordered
+func compareValues(a interface{}, b interface{}) (int, bool) {
+	if ta, ok := a.(time.Time); ok {
+		tb, ok := b.(time.Time)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case ta.Before(tb):
+			return -1, true
+		case ta.After(tb):
+			return 1, true
+		}
+		return 0, true
+	}
+
+	if ra, ok := a.(*big.Rat); ok {
+		rb, ok := toRat(b)
+		if !ok {
+			return 0, false
+		}
+		return ra.Cmp(rb), true
+	}
+	if rb, ok := b.(*big.Rat); ok {
+		ra, ok := toRat(a)
+		if !ok {
+			return 0, false
+		}
+		return ra.Cmp(rb), true
+	}
+
+	fa, okA := toFloat(a)
+	fb, okB := toFloat(b)
+	if !okA || !okB {
+		return 0, false
+	}
+	switch {
+	case fa < fb:
+		return -1, true
+	case fa > fb:
+		return 1, true
+	}
+	return 0, true
+}
+
+func toFloat(val interface{}) (float64, bool) {
+	v := reflect.ValueOf(val)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
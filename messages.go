@@ -0,0 +1,177 @@
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Catalog looks up the message template for an error code in a locale.
+// Templates may use {field}, {value} and any of the error's params, e.g.
+// {min}, {max} or {allowed}. When an error comes with a {suggestion}, the
+// template for its code plus "_suggestion" is preferred.
+type Catalog interface {
+	Template(locale string, code string) (string, bool)
+}
+
+// MessageCatalog is a Catalog keyed by locale and then by error code
+type MessageCatalog map[string]map[string]string
+
+func (c MessageCatalog) Template(locale string, code string) (string, bool) {
+	template, ok := c[locale][code]
+	return template, ok
+}
+
+// Used unless overridden with Locale(...) / Catalog(...). Add a locale with
+// e.g. DefaultCatalog["de"] = map[string]string{CodeRequired: "{field} fehlt"}
+var DefaultLocale = "en"
+var DefaultCatalog = MessageCatalog{
+	"en": {
+		CodeType:                    "{field} must be of type {expected}",
+		CodeRequired:                "{field} is required",
+		CodeNull:                    "{field} can't be null",
+		CodeMin:                     "{field} must be at least {min}",
+		CodeMax:                     "{field} must be at most {max}",
+		CodeRegex:                   "{field} is not in the expected format",
+		CodeIn:                      "{field} must be one of {allowed}",
+		CodeBefore:                  "{field} must be before {before}",
+		CodeAfter:                   "{field} must be after {after}",
+		CodeLayout:                  "{field} must be a time like {layouts}",
+		CodeEpoch:                   "{field} must be a timestamp in {unit} between {min_year} and {max_year}",
+		CodePast:                    "{field} must be in the past",
+		CodeFuture:                  "{field} must be in the future",
+		CodeWithinLast:              "{field} must be within the last {duration}",
+		CodeWithinNext:              "{field} must be within the next {duration}",
+		CodeMinAge:                  "{field} must be at least {years} years ago",
+		CodeCustom:                  "{field} is invalid",
+		CodeUnknown:                 "unknown field `{field}`",
+		CodeUnknown + "_suggestion": "unknown field `{field}`, did you mean `{suggestion}`?",
+		CodeOverflow:                "{field} doesn't fit in {type}",
+		CodeInteger:                 "{field} must be a whole number",
+		CodePrecision:               "{field} must have at most {precision} digits",
+		CodeScale:                   "{field} must have at most {scale} decimal places",
+		CodeEqualsField:             "{field} must match {other}",
+		CodeGreaterThanField:        "{field} must be greater than {other}",
+		CodeGreaterOrEqualField:     "{field} must be at least {other}",
+		CodeAfterField:              "{field} must be after {other}",
+		CodeBeforeField:             "{field} must be before {other}",
+		CodeMinItems:                "{field} must have at least {min} items",
+		CodeMaxItems:                "{field} must have at most {max} items",
+		CodeUnique:                  "{field} repeats {value}",
+		CodeMaxFiles:                "{field} accepts at most {max} files",
+		CodeMaxSize:                 "{value} is larger than {max} bytes",
+		CodeContentType:             "{value} must be one of {allowed}",
+		CodeExtension:               "{value} must end in one of {allowed}",
+		CodeFile:                    "{value} could not be read",
+		CodeMalformed:               "The request could not be parsed: {error}",
+		CodeBodyTooLarge:            "The request body is larger than {max} bytes",
+		CodeBodyTooDeep:             "The request body nests deeper than {max} levels",
+	},
+}
+
+// message renders the message for an error. A rule's own Message wins, then
+// the template for the error's code in the chosen locale and then in the
+// default locale. Errors returned by a Check keep their own message unless
+// the rule has one.
+func (v *ValidationData) message(rule *Rule, ve *ValidationError) string {
+	if rule != nil && rule.Message != "" {
+		return renderMessage(rule.Message, ve, rule.params())
+	}
+	if ve.Err != nil {
+		return ve.Message
+	}
+	codes := []string{ve.Code}
+	if _, ok := ve.Params["suggestion"]; ok {
+		codes = []string{ve.Code + "_suggestion", ve.Code}
+	}
+	for _, code := range codes {
+		if template, ok := v.catalog.Template(v.locale, code); ok {
+			return renderMessage(template, ve, rule.params())
+		}
+		if template, ok := DefaultCatalog.Template(DefaultLocale, code); ok {
+			return renderMessage(template, ve, rule.params())
+		}
+	}
+
+	return ve.Message
+}
+
+/* * * * * * * * * * * * *
+  Helper Functions
+* * * * * * * * * * * * */
+
+var placeholder = regexp.MustCompile(`\{(\w+)\}`)
+
+// params lists the limits set on a rule, so a message can mention {max} even
+// when it's {min} that failed
+func (rule *Rule) params() map[string]interface{} {
+	params := map[string]interface{}{}
+	if rule == nil {
+		return params
+	}
+
+	if rule.DidSetMin {
+		params["min"] = rule.Min
+	}
+	if rule.DidSetMax {
+		params["max"] = rule.Max
+	}
+	if len(rule.In) > 0 {
+		params["allowed"] = rule.In
+	}
+	if rule.Regex != "" {
+		params["regex"] = rule.Regex
+	}
+	if rule.Before != nil {
+		params["before"] = *rule.Before
+	}
+	if rule.After != nil {
+		params["after"] = *rule.After
+	}
+	return params
+}
+
+// renderMessage fills in {field}, {value}, the error's params and then the
+// rule's; unknown placeholders are left alone
+func renderMessage(template string, ve *ValidationError, ruleParams map[string]interface{}) string {
+	return placeholder.ReplaceAllStringFunc(template, func(match string) string {
+		name := match[1 : len(match)-1]
+		switch name {
+		case "field":
+			if ve.Field == "" {
+				return "value"
+			}
+			return ve.Field
+		case "value":
+			return formatParam(ve.Value)
+		}
+		if param, ok := ve.Params[name]; ok {
+			return formatParam(param)
+		}
+		if param, ok := ruleParams[name]; ok {
+			return formatParam(param)
+		}
+		return match
+	})
+}
+
+func formatParam(val interface{}) string {
+	switch val.(type) {
+	case time.Time:
+		return val.(time.Time).Format(time.RFC3339)
+	case string:
+		return val.(string)
+	}
+
+	v := reflect.ValueOf(val)
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = formatParam(v.Index(i).Interface())
+		}
+		return strings.Join(items, ", ")
+	}
+	return fmt.Sprint(val)
+}
@@ -0,0 +1,47 @@
+package validate_test
+
+import (
+	. "github.com/franela/goblin"
+	. "github.com/joslinm/validate"
+	"testing"
+)
+
+func TestMessages(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Messages", func() {
+		input := map[string]interface{}{"age": 12, "plan": "gold"}
+		rules := RuleBook{
+			"age":  RB.Min(18).Max(150),
+			"plan": RB.In([]string{"free", "pro"}),
+		}
+
+		g.It("Should render English messages by default", func() {
+			_, errs := Validate(input).With(rules)
+			g.Assert(errs["age"][0].Error()).Equal("age must be at least 18")
+			g.Assert(errs["plan"][0].Error()).Equal("plan must be one of free, pro")
+		})
+		g.It("Should let a rule's Message override the default", func() {
+			_, errs := Validate(input).With(RuleBook{
+				"age": RB.Min(18).Max(150).Message("{field} was {value}, needs {min} to {max}"),
+			})
+			g.Assert(errs["age"][0].Error()).Equal("age was 12, needs 18 to 150")
+		})
+		g.It("Should render messages from the chosen locale", func() {
+			catalog := MessageCatalog{
+				"de": {
+					CodeMin: "{field} muss mindestens {min} sein",
+				},
+				"ja": {
+					CodeMin: "{field}は{min}以上である必要があります",
+				},
+			}
+			_, errs := Validate(input).Catalog(catalog).Locale("de").With(rules)
+			g.Assert(errs["age"][0].Error()).Equal("age muss mindestens 18 sein")
+			// falls back to the default locale for missing templates
+			g.Assert(errs["plan"][0].Error()).Equal("plan must be one of free, pro")
+
+			_, errs = Validate(input).Catalog(catalog).Locale("ja").With(rules)
+			g.Assert(errs["age"][0].Error()).Equal("ageは18以上である必要があります")
+		})
+	})
+}
@@ -0,0 +1,89 @@
+package validate
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+type paramsKey struct{}
+
+// MiddlewareOption configures Middleware(...)
+type MiddlewareOption func(*middleware)
+
+type middleware struct {
+	rules     RuleBook
+	configure []func(*ValidationData) *ValidationData
+	onError   func(w http.ResponseWriter, r *http.Request, errs map[string][]error)
+}
+
+// Middleware validates every request against rules before handing it on. A
+// request that fails is answered with WriteProblem(...) (or the OnError
+// handler); one that passes carries its params in its context, see
+// ParamsFrom(...).
+func Middleware(rules RuleBook, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	m := &middleware{
+		rules: rules,
+		onError: func(w http.ResponseWriter, r *http.Request, errs map[string][]error) {
+			WriteProblem(w, errs)
+		},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			v := Validate(r)
+			for _, configure := range m.configure {
+				v = configure(v)
+			}
+
+			params, errs := v.With(m.rules)
+			if len(errs) > 0 {
+				Log.Info("Rejecting %v %v: %v", r.Method, r.URL.Path, errs)
+				m.onError(w, r, errs)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), paramsKey{}, params)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Configure adjusts the ValidationData used for each request, e.g.
+//
+//	validate.Configure(func(v *validate.ValidationData) *validate.ValidationData {
+//		return v.MaxBodySize(64 << 10).Locale("de")
+//	})
+func Configure(fn func(*ValidationData) *ValidationData) MiddlewareOption {
+	return func(m *middleware) {
+		m.configure = append(m.configure, fn)
+	}
+}
+
+// OnError replaces the default Problem Details response for failed requests
+func OnError(fn func(w http.ResponseWriter, r *http.Request, errs map[string][]error)) MiddlewareOption {
+	return func(m *middleware) {
+		m.onError = fn
+	}
+}
+
+// ParamsFrom returns the params Middleware(...) validated for a request
+func ParamsFrom(ctx context.Context) (map[string]interface{}, bool) {
+	params, ok := ctx.Value(paramsKey{}).(map[string]interface{})
+	return params, ok
+}
+
+// BindParams binds the params Middleware(...) validated for a request into
+// dst, see Bind(...). A context without params is reported under RequestKey.
+func BindParams(ctx context.Context, dst interface{}) map[string][]error {
+	params, ok := ParamsFrom(ctx)
+	if !ok {
+		errs := make(map[string][]error)
+		addError(errs, RequestKey, errors.New("No params in context; is the handler behind Middleware(...)?"))
+		return errs
+	}
+	return Bind(params, dst)
+}
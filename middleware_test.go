@@ -0,0 +1,81 @@
+package validate_test
+
+import (
+	. "github.com/franela/goblin"
+	. "github.com/joslinm/validate"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Middleware", func() {
+		var seen map[string]interface{}
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen, _ = ParamsFrom(r.Context())
+			w.WriteHeader(http.StatusNoContent)
+		})
+		rules := RuleBook{
+			"page": RB.Min(1).Default(1),
+			"q":    RB.Required().String(),
+		}
+
+		g.It("Should inject validated params into the request context", func() {
+			seen = nil
+			w := httptest.NewRecorder()
+			Middleware(rules)(handler).ServeHTTP(w, httptest.NewRequest("GET", "/?q=cats", nil))
+			g.Assert(w.Code).Equal(http.StatusNoContent)
+			g.Assert(seen["q"]).Equal("cats")
+			g.Assert(seen["page"]).Equal(1)
+		})
+		g.It("Should answer invalid requests without calling the handler", func() {
+			seen = nil
+			w := httptest.NewRecorder()
+			Middleware(rules)(handler).ServeHTTP(w, httptest.NewRequest("GET", "/?page=0", nil))
+			g.Assert(w.Code).Equal(http.StatusUnprocessableEntity)
+			g.Assert(w.Header().Get("Content-Type")).Equal("application/problem+json")
+			g.Assert(seen == nil).IsTrue()
+		})
+		g.It("Should apply Configure and OnError options", func() {
+			var failed map[string][]error
+			mw := Middleware(rules,
+				Configure(func(v *ValidationData) *ValidationData {
+					return v.MaxBodySize(4)
+				}),
+				OnError(func(w http.ResponseWriter, r *http.Request, errs map[string][]error) {
+					failed = errs
+					w.WriteHeader(http.StatusTeapot)
+				}),
+			)
+			req := httptest.NewRequest("POST", "/", strings.NewReader(`{"q": "cats"}`))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			mw(handler).ServeHTTP(w, req)
+			g.Assert(w.Code).Equal(http.StatusTeapot)
+			g.Assert(len(failed[RequestKey])).Equal(1)
+		})
+		g.It("Should report no params outside the middleware", func() {
+			_, ok := ParamsFrom(httptest.NewRequest("GET", "/", nil).Context())
+			g.Assert(ok).IsFalse()
+		})
+		g.It("Should bind validated params into a struct", func() {
+			type search struct {
+				Q    string `json:"q"`
+				Page int    `json:"page"`
+			}
+			var dst search
+			var errs map[string][]error
+			binding := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				errs = BindParams(r.Context(), &dst)
+			})
+			Middleware(rules)(binding).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/?q=cats&page=3", nil))
+			g.Assert(len(errs)).Equal(0)
+			g.Assert(dst).Equal(search{Q: "cats", Page: 3})
+
+			errs = BindParams(httptest.NewRequest("GET", "/", nil).Context(), &dst)
+			g.Assert(len(errs[RequestKey])).Equal(1)
+		})
+	})
+}
@@ -0,0 +1,138 @@
+package validate
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// coerceNumber converts any Go number or numeric string to the type of an
+// Int (int64), Uint (uint64) or Float (float64) rule, refusing values that
+// don't fit in its Bits or, for the integer types, aren't whole numbers
+func (rule *Rule) coerceNumber(input interface{}) (interface{}, error) {
+	v := reflect.ValueOf(input)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rule.fromInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rule.fromUint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rule.fromFloat(v.Float(), input)
+	case reflect.String:
+		return rule.fromString(v.String())
+	}
+
+	return input, ConversionError(input, typeName(rule.Type))
+}
+
+func (rule *Rule) fromInt(i int64) (interface{}, error) {
+	switch rule.Type {
+	case Uint:
+		if i < 0 {
+			return i, overflowError(i, rule.numberType())
+		}
+		return rule.fromUint(uint64(i))
+	case Float:
+		return rule.fromFloat(float64(i), i)
+	}
+
+	bits := rule.bits()
+	if bits < 64 && (i < -1<<(bits-1) || i > 1<<(bits-1)-1) {
+		return i, overflowError(i, rule.numberType())
+	}
+	return i, nil
+}
+
+func (rule *Rule) fromUint(u uint64) (interface{}, error) {
+	switch rule.Type {
+	case Int:
+		if u > math.MaxInt64 {
+			return u, overflowError(u, rule.numberType())
+		}
+		return rule.fromInt(int64(u))
+	case Float:
+		return rule.fromFloat(float64(u), u)
+	}
+
+	if bits := rule.bits(); bits < 64 && u > 1<<bits-1 {
+		return u, overflowError(u, rule.numberType())
+	}
+	return u, nil
+}
+
+// fromFloat takes the original input too, so errors report what was given
+func (rule *Rule) fromFloat(f float64, input interface{}) (interface{}, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return input, ConversionError(input, typeName(rule.Type))
+	}
+
+	switch rule.Type {
+	case Int:
+		if f != math.Trunc(f) {
+			return input, integerError(input)
+		}
+		if f < math.MinInt64 || f >= math.MaxInt64 {
+			return input, overflowError(input, rule.numberType())
+		}
+		return rule.fromInt(int64(f))
+	case Uint:
+		if f != math.Trunc(f) {
+			return input, integerError(input)
+		}
+		if f < 0 || f >= math.MaxUint64 {
+			return input, overflowError(input, rule.numberType())
+		}
+		return rule.fromUint(uint64(f))
+	}
+
+	if rule.bits() == 32 && math.Abs(f) > math.MaxFloat32 {
+		return input, overflowError(input, rule.numberType())
+	}
+	return f, nil
+}
+
+// fromString parses integers exactly, falling back to a float so that e.g.
+// "5.0" is accepted and "5.5" reported as not whole
+func (rule *Rule) fromString(str string) (interface{}, error) {
+	var err error
+	switch rule.Type {
+	case Int:
+		var i int64
+		if i, err = strconv.ParseInt(str, 10, 64); err == nil {
+			return rule.fromInt(i)
+		}
+	case Uint:
+		var u uint64
+		if u, err = strconv.ParseUint(str, 10, 64); err == nil {
+			return rule.fromUint(u)
+		}
+	}
+	if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+		return str, overflowError(str, rule.numberType())
+	}
+
+	f, err := strconv.ParseFloat(str, 64)
+	if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+		return str, overflowError(str, rule.numberType())
+	} else if err != nil {
+		return str, ConversionError(str, typeName(rule.Type))
+	}
+	return rule.fromFloat(f, str)
+}
+
+/* * * * * * * * * * * * *
+  Helper Functions
+* * * * * * * * * * * * */
+
+// bits defaults to 64
+func (rule *Rule) bits() int {
+	if rule.Bits == 0 {
+		return 64
+	}
+	return rule.Bits
+}
+
+// numberType names the Go type a rule's numbers fit in, e.g. "int16"
+func (rule *Rule) numberType() string {
+	return typeName(rule.Type) + strconv.Itoa(rule.bits())
+}
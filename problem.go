@@ -0,0 +1,86 @@
+package validate
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ProblemType is the "type" of every ProblemDetails; point it at your own
+// documentation if you have some
+var ProblemType = "about:blank"
+
+// ProblemDetails is an RFC 7807 (application/problem+json) body describing a
+// failed validation
+type ProblemDetails struct {
+	Type          string         `json:"type"`
+	Title         string         `json:"title"`
+	Status        int            `json:"status"`
+	Detail        string         `json:"detail,omitempty"`
+	InvalidParams []InvalidParam `json:"invalid-params"`
+}
+
+// InvalidParam is a single entry of ProblemDetails.InvalidParams
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Code   string `json:"code"`
+	Reason string `json:"reason"`
+}
+
+// Problem turns the errors returned by With(...), Map(...) or Request(...)
+// into ProblemDetails. Errors for the request as a whole (under RequestKey)
+// become the detail and decide the status: 413 for an oversized body, 400
+// for one that can't be parsed. Otherwise the status is 422.
+func Problem(errs map[string][]error) *ProblemDetails {
+	problem := &ProblemDetails{
+		Type:          ProblemType,
+		Status:        http.StatusUnprocessableEntity,
+		InvalidParams: []InvalidParam{},
+	}
+
+	var details []string
+	for _, err := range errs[RequestKey] {
+		details = append(details, err.Error())
+		problem.Status = http.StatusBadRequest
+		var ve *ValidationError
+		if errors.As(err, &ve) && ve.Code == CodeBodyTooLarge {
+			problem.Status = http.StatusRequestEntityTooLarge
+		}
+	}
+	problem.Detail = strings.Join(details, "; ")
+	problem.Title = http.StatusText(problem.Status)
+
+	var keys []string
+	for key := range errs {
+		if key != RequestKey {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, err := range errs[key] {
+			param := InvalidParam{Name: key, Code: CodeCustom, Reason: err.Error()}
+			var ve *ValidationError
+			if errors.As(err, &ve) {
+				param.Code = ve.Code
+				if ve.Field != "" {
+					param.Name = ve.Field
+				}
+			}
+			problem.InvalidParams = append(problem.InvalidParams, param)
+		}
+	}
+
+	return problem
+}
+
+// WriteProblem writes errs to w as an application/problem+json response
+func WriteProblem(w http.ResponseWriter, errs map[string][]error) error {
+	problem := Problem(errs)
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+	return json.NewEncoder(w).Encode(problem)
+}
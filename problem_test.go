@@ -0,0 +1,59 @@
+package validate_test
+
+import (
+	"encoding/json"
+	. "github.com/franela/goblin"
+	. "github.com/joslinm/validate"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProblem(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Problem Details", func() {
+		g.It("Should write a 422 with every invalid param", func() {
+			_, errs := Validate(map[string]interface{}{
+				"age":  12,
+				"tags": []interface{}{"a", 1},
+			}).With(RuleBook{
+				"age":   RB.Min(18),
+				"email": RB.Required().Email(),
+				"tags":  RB.Each(RB.String()),
+			})
+
+			w := httptest.NewRecorder()
+			WriteProblem(w, errs)
+			g.Assert(w.Code).Equal(422)
+			g.Assert(w.Header().Get("Content-Type")).Equal("application/problem+json")
+
+			var problem ProblemDetails
+			g.Assert(json.Unmarshal(w.Body.Bytes(), &problem) == nil).IsTrue()
+			g.Assert(problem.Status).Equal(422)
+			g.Assert(problem.InvalidParams).Equal([]InvalidParam{
+				{Name: "age", Code: CodeMin, Reason: "age must be at least 18"},
+				{Name: "email", Code: CodeRequired, Reason: "email is required"},
+				{Name: "tags[1]", Code: CodeType, Reason: "tags[1] must be of type string"},
+			})
+		})
+		g.It("Should use the invalid-params member name", func() {
+			_, errs := Validate(map[string]interface{}{}).With(RuleBook{"x": RB.Required()})
+			w := httptest.NewRecorder()
+			WriteProblem(w, errs)
+			g.Assert(strings.Contains(w.Body.String(), `"invalid-params":[{"name":"x"`)).IsTrue()
+		})
+		g.It("Should answer 400 for a malformed body and 413 for an oversized one", func() {
+			req := httptest.NewRequest("POST", "/", strings.NewReader(`{"x": `))
+			req.Header.Set("Content-Type", "application/json")
+			_, errs := Request(req, RuleBook{})
+			problem := Problem(errs)
+			g.Assert(problem.Status).Equal(400)
+			g.Assert(problem.Detail != "").IsTrue()
+
+			req = httptest.NewRequest("POST", "/", strings.NewReader(`{"x": "0123456789"}`))
+			req.Header.Set("Content-Type", "application/json")
+			_, errs = Validate(req).MaxBodySize(4).With(RuleBook{})
+			g.Assert(Problem(errs).Status).Equal(413)
+		})
+	})
+}
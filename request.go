@@ -0,0 +1,282 @@
+package validate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// RequestKey is the key under which errors that aren't tied to a single
+// parameter (e.g. a malformed body) are reported
+const RequestKey = "_request"
+
+// Request limits used unless overridden with MaxBodySize(...) / MaxDepth(...)
+var DefaultMaxBodySize int64 = 1 << 20 // 1MB
+var DefaultMaxDepth = 32
+
+// Validates the query string and form-encoded or JSON body of a request
+func Request(given *http.Request, expected RuleBook) (map[string]interface{}, map[string][]error) {
+	return Validate(given).With(expected)
+}
+
+func (v *ValidationData) request(given *http.Request, expected RuleBook) (map[string]interface{}, map[string][]error) {
+	var data map[string]interface{}
+	var err error
+
+	if isJSON(given) {
+		data, err = v.decodeJSON(given, expected)
+	} else if isMultipart(given) {
+		data, err = v.parseMultipart(given, expected)
+	} else {
+		if given.Body != nil {
+			given.Body = http.MaxBytesReader(nil, given.Body, v.maxBodySize)
+		}
+		err = given.ParseForm()
+		data = formValues(given.Form, expected)
+	}
+
+	if err != nil {
+		Log.Warning("Could not parse request: %v", err)
+		errors := make(map[string][]error)
+		ve := addError(errors, RequestKey, requestError(err))
+		ve.Message = v.message(nil, ve)
+		return map[string]interface{}{}, errors
+	}
+
+	return v.validateDocument(&Document{Data: data, Request: given}, expected)
+}
+
+// decodeJSON reads a JSON object out of the request body, enforcing the body
+// size and nesting depth limits. Query parameters are included as well but
+// the body takes precedence.
+func (v *ValidationData) decodeJSON(given *http.Request, expected RuleBook) (map[string]interface{}, error) {
+	data := formValues(given.URL.Query(), expected)
+	if given.Body == nil {
+		return data, nil
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(given.Body, v.maxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > v.maxBodySize {
+		return nil, NewError(CodeBodyTooLarge, nil, map[string]interface{}{"max": v.maxBodySize},
+			"Request body exceeds %v bytes", v.maxBodySize)
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return data, nil
+	}
+	if err := checkDepth(body, v.maxDepth); err != nil {
+		return nil, err
+	}
+
+	// numbers are decoded as json.Number so large ones can reach their rule
+	// intact, then turned into float64 wherever no rule needs that
+	var decoded interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, NewError(CodeMalformed, nil, map[string]interface{}{"error": err.Error()}, "Could not decode JSON body: %v", err)
+	}
+	object, ok := decoded.(map[string]interface{})
+	if !ok {
+		return nil, NewError(CodeMalformed, nil, map[string]interface{}{"error": fmt.Sprintf("expecting a JSON object, got %T", decoded)},
+			"Expecting a JSON object. Got: %T", decoded)
+	}
+	if _, err := floatNumbers(object, nil, expected); err != nil {
+		return nil, err
+	}
+
+	for k, val := range object {
+		data[k] = val
+	}
+	return data, nil
+}
+
+// parseMultipart reads a multipart/form-data body. Uploaded files are given
+// to the rules as []*multipart.FileHeader.
+func (v *ValidationData) parseMultipart(given *http.Request, expected RuleBook) (map[string]interface{}, error) {
+	given.Body = http.MaxBytesReader(nil, given.Body, v.maxBodySize)
+	if err := given.ParseMultipartForm(v.maxBodySize); err != nil {
+		return nil, err
+	}
+
+	data := formValues(given.Form, expected)
+	for k, files := range given.MultipartForm.File {
+		data[k] = files
+	}
+	return data, nil
+}
+
+/* * * * * * * * * * * * *
+  Helper Functions
+* * * * * * * * * * * * */
+
+// requestError gives parsing errors from net/http a code
+func requestError(err error) error {
+	var ve *ValidationError
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &ve) {
+		return ve
+	} else if errors.As(err, &tooLarge) {
+		return NewError(CodeBodyTooLarge, nil, map[string]interface{}{"max": tooLarge.Limit},
+			"Request body exceeds %v bytes", tooLarge.Limit)
+	}
+
+	ve = NewError(CodeMalformed, nil, map[string]interface{}{"error": err.Error()}, "Could not parse request: %v", err)
+	ve.Err = err
+	return ve
+}
+
+// listNames are the form keys read by Slice rules
+func listNames(expected RuleBook) map[string]bool {
+	names := make(map[string]bool)
+	for k, v := range expected {
+		rb, ok := v.(ruleBuilder)
+		if !ok {
+			continue
+		}
+		if rule := rb.Build(); rule.Type == Slice {
+			for _, name := range rule.names(k) {
+				names[name] = true
+			}
+		}
+	}
+	return names
+}
+
+// floatNumbers replaces the json.Numbers of a decoded body with float64, as
+// plain decoding would, except those read by a rule that needs the exact
+// literal. Maps and slices are changed in place.
+func floatNumbers(val interface{}, rule *Rule, book RuleBook) (interface{}, error) {
+	var err error
+	switch val := val.(type) {
+	case json.Number:
+		if rule != nil && rule.exact() {
+			return val, nil
+		}
+		f, err := val.Float64()
+		if err != nil {
+			return nil, NewError(CodeMalformed, nil, map[string]interface{}{"error": err.Error()}, "Could not decode JSON body: %v", err)
+		}
+		return f, nil
+	case []interface{}:
+		var element *Rule
+		if rule != nil {
+			element = rule.Element
+		}
+		for i, item := range val {
+			if val[i], err = floatNumbers(item, element, nil); err != nil {
+				return nil, err
+			}
+		}
+	case map[string]interface{}:
+		rules, books := make(map[string]*Rule), make(map[string]RuleBook)
+		for k, v := range book {
+			switch v := v.(type) {
+			case ruleBuilder:
+				rule := v.Build()
+				for _, name := range rule.names(k) {
+					rules[name] = &rule
+				}
+			case RuleBook:
+				books[k] = v
+			}
+		}
+		for k, item := range val {
+			if val[k], err = floatNumbers(item, rules[k], books[k]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return val, nil
+}
+
+// exact rules lose something if given a float64 instead of the literal
+func (rule *Rule) exact() bool {
+	switch rule.Type {
+	case Int, Uint, Decimal:
+		return true
+	case Time:
+		return rule.Epoch != 0
+	}
+	return false
+}
+
+func mediaType(given *http.Request) string {
+	mediaType, _, err := mime.ParseMediaType(given.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+func isJSON(given *http.Request) bool {
+	mediaType := mediaType(given)
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func isMultipart(given *http.Request) bool {
+	return mediaType(given) == "multipart/form-data"
+}
+
+// checkDepth walks the tokens of a JSON document and fails as soon as objects
+// or arrays nest deeper than max, before anything is allocated for them
+func checkDepth(body []byte, max int) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return NewError(CodeMalformed, nil, map[string]interface{}{"error": err.Error()}, "Could not decode JSON body: %v", err)
+		}
+
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+			if depth > max {
+				return NewError(CodeBodyTooDeep, nil, map[string]interface{}{"max": max},
+					"JSON body nests deeper than %v levels", max)
+			}
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+}
+
+// formValues flattens url.Values into plain strings; the rules will coerce
+// them just like any other string input. Keys read by a Slice rule get every
+// value as a list, since ?tag=a is just as much a list as ?tag=a&tag=b. Any
+// other key gets its first value, which like FormValue(...) is the body's
+// before the query string's.
+func formValues(form url.Values, expected RuleBook) map[string]interface{} {
+	lists := listNames(expected)
+	given := make(map[string]interface{})
+	for k, vals := range form {
+		if len(vals) == 0 {
+			continue
+		}
+		if !lists[k] {
+			given[k] = vals[0]
+			continue
+		}
+		list := make([]interface{}, len(vals))
+		for i, val := range vals {
+			list[i] = val
+		}
+		given[k] = list
+	}
+
+	Log.Debug("formValues(...) -> %v", given)
+	return given
+}
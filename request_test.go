@@ -0,0 +1,207 @@
+package validate_test
+
+import (
+	"bytes"
+	. "github.com/franela/goblin"
+	. "github.com/joslinm/validate"
+	"math/big"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRequest(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Request", func() {
+		g.Describe("Query string", func() {
+			g.It("Should coerce and validate query parameters", func() {
+				req := httptest.NewRequest("GET", "/?x=4&y=hi!", nil)
+				params, errors := Validate(req).With(RuleBook{
+					"x": RB.Min(1),
+					"y": RB.Regex("hi.*"),
+				})
+				g.Assert(len(errors)).Equal(0)
+				g.Assert(params["x"]).Equal(float64(4))
+				g.Assert(params["y"]).Equal("hi!")
+			})
+			g.It("Should treat repeated and lone values as lists for Each rules", func() {
+				req := httptest.NewRequest("GET", "/?tag=a&tag=b&id=7", nil)
+				params, errors := Request(req, RuleBook{
+					"tag": RB.Each(RB.String()),
+					"id":  RB.Each(RB.Number()),
+				})
+				g.Assert(len(errors)).Equal(0)
+				g.Assert(params["tag"]).Equal([]interface{}{"a", "b"})
+				g.Assert(params["id"]).Equal([]interface{}{float64(7)})
+			})
+			g.It("Should return errors for failed parameters", func() {
+				req := httptest.NewRequest("GET", "/?x=0&y=nope", nil)
+				_, errors := Request(req, RuleBook{
+					"x": RB.Min(1),
+					"y": RB.Regex("hi.*"),
+				})
+				g.Assert(errors["x"] != nil).IsTrue()
+				g.Assert(errors["y"] != nil).IsTrue()
+			})
+		})
+
+		g.Describe("Form body", func() {
+			g.It("Should validate form-encoded parameters", func() {
+				form := url.Values{"flag": {"true"}}
+				req := httptest.NewRequest("POST", "/", strings.NewReader(form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				params, errors := Request(req, RuleBook{
+					"flag": RB.Bool(),
+				})
+				g.Assert(len(errors)).Equal(0)
+				g.Assert(params["flag"]).Equal(true)
+			})
+			g.It("Should take a single value for non-Slice rules, body first", func() {
+				body := url.Values{"id": {"2"}, "tag": {"c"}}
+				req := httptest.NewRequest("POST", "/?id=1&tag=a&tag=b", strings.NewReader(body.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				params, errors := Request(req, RuleBook{
+					"id":  RB.String(),
+					"tag": RB.Each(RB.String()),
+				})
+				g.Assert(len(errors)).Equal(0)
+				g.Assert(params["id"]).Equal("2")
+				g.Assert(params["tag"]).Equal([]interface{}{"c", "a", "b"})
+			})
+			g.It("Should reject a body over the size limit", func() {
+				body := "x=" + strings.Repeat("a", 2<<20)
+				req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				_, errors := Validate(req).MaxBodySize(1024).With(RuleBook{"x": RB.String()})
+				g.Assert(len(errors[RequestKey])).Equal(1)
+				g.Assert(errors[RequestKey][0].(*ValidationError).Code).Equal(CodeBodyTooLarge)
+			})
+			g.It("Should report a malformed body under RequestKey", func() {
+				req := httptest.NewRequest("POST", "/", strings.NewReader("%zz"))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				_, errors := Request(req, RuleBook{})
+				g.Assert(errors[RequestKey] != nil).IsTrue()
+			})
+		})
+
+		g.Describe("JSON body", func() {
+			jsonRequest := func(body string) *http.Request {
+				req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+				req.Header.Set("Content-Type", "application/json; charset=utf-8")
+				return req
+			}
+
+			g.It("Should decode and validate a JSON object", func() {
+				params, errors := Request(jsonRequest(`{"x": 4, "flag": false}`), RuleBook{
+					"x":    RB.Min(1),
+					"flag": RB.Bool(),
+				})
+				g.Assert(len(errors)).Equal(0)
+				g.Assert(params["x"]).Equal(float64(4))
+				g.Assert(params["flag"]).Equal(false)
+			})
+			g.It("Should keep large JSON numbers exact", func() {
+				params, errors := Request(jsonRequest(`{"id": 9007199254740993, "price": 12345678901234567.89}`), RuleBook{
+					"id":    RB.Int(),
+					"price": RB.Decimal().Scale(2),
+				})
+				g.Assert(len(errors)).Equal(0)
+				g.Assert(params["id"]).Equal(int64(9007199254740993))
+				g.Assert(params["price"].(*big.Rat).FloatString(2)).Equal("12345678901234567.89")
+			})
+			g.It("Should only keep JSON numbers exact where a rule needs them", func() {
+				var prepared interface{}
+				body := `{"id": 1, "n": 2, "list": [3], "known": {"x": 4}, "extra": 5}`
+				params, errors := Validate(jsonRequest(body)).Passthrough().With(RuleBook{
+					"id": RB.Int(),
+					"n": RB.Number().Prepare(func(val interface{}) interface{} {
+						prepared = val
+						return val
+					}),
+					"list":  RB.Slice(),
+					"known": RuleBook{"x": RB.Min(1)},
+				})
+				g.Assert(len(errors)).Equal(0)
+				g.Assert(params["id"]).Equal(int64(1))
+				g.Assert(prepared).Equal(float64(2))
+				g.Assert(params["list"]).Equal([]interface{}{float64(3)})
+				g.Assert(params["known"]).Equal(map[string]interface{}{"x": float64(4)})
+				g.Assert(params["extra"]).Equal(float64(5))
+			})
+			g.It("Should report malformed JSON under RequestKey", func() {
+				_, errors := Request(jsonRequest(`{"x": `), RuleBook{"x": RB.Min(1)})
+				g.Assert(len(errors[RequestKey])).Equal(1)
+			})
+			g.It("Should reject a body that is not an object", func() {
+				_, errors := Request(jsonRequest(`[1, 2]`), RuleBook{})
+				g.Assert(len(errors[RequestKey])).Equal(1)
+			})
+			g.It("Should reject a body over the size limit", func() {
+				_, errors := Validate(jsonRequest(`{"x": "0123456789"}`)).MaxBodySize(8).With(RuleBook{})
+				g.Assert(len(errors[RequestKey])).Equal(1)
+			})
+			g.It("Should reject a body nested past the depth limit", func() {
+				body := `{"a": {"b": {"c": {}}}}`
+				_, errors := Validate(jsonRequest(body)).MaxDepth(3).With(RuleBook{})
+				g.Assert(len(errors[RequestKey])).Equal(1)
+				_, errors = Validate(jsonRequest(body)).MaxDepth(4).With(RuleBook{})
+				g.Assert(len(errors)).Equal(0)
+			})
+		})
+
+		g.Describe("Multipart body", func() {
+			png := append([]byte("\x89PNG\x0d\x0a\x1a\x0a"), make([]byte, 64)...)
+			multipartRequest := func(fields map[string]string, files map[string][]byte) *http.Request {
+				body := &bytes.Buffer{}
+				writer := multipart.NewWriter(body)
+				for k, v := range fields {
+					writer.WriteField(k, v)
+				}
+				for name, content := range files {
+					part, _ := writer.CreateFormFile("upload", name)
+					part.Write(content)
+				}
+				writer.Close()
+
+				req := httptest.NewRequest("POST", "/", body)
+				req.Header.Set("Content-Type", writer.FormDataContentType())
+				return req
+			}
+
+			g.It("Should accept a file passing every rule", func() {
+				req := multipartRequest(map[string]string{"title": "cat"}, map[string][]byte{"cat.png": png})
+				params, errors := Request(req, RuleBook{
+					"title":  RB.String(),
+					"upload": RB.MaxSize(1024).ContentTypes("image/*").Extensions("png", ".jpg"),
+				})
+				g.Assert(len(errors)).Equal(0)
+				g.Assert(params["title"]).Equal("cat")
+				files := params["upload"].([]*multipart.FileHeader)
+				g.Assert(files[0].Filename).Equal("cat.png")
+			})
+			g.It("Should sniff the content type instead of trusting the name", func() {
+				req := multipartRequest(nil, map[string][]byte{"cat.png": []byte("#!/bin/sh\necho hi")})
+				_, errors := Request(req, RuleBook{
+					"upload": RB.ContentTypes("image/png"),
+				})
+				g.Assert(len(errors["upload"])).Equal(1)
+			})
+			g.It("Should error on oversized files, bad extensions and too many files", func() {
+				req := multipartRequest(nil, map[string][]byte{"a.gif": png, "b.png": png})
+				_, errors := Request(req, RuleBook{
+					"upload": RB.MaxFiles(1).MaxSize(8).Extensions("png"),
+				})
+				// 1 count + 2 size + 1 extension
+				g.Assert(len(errors["upload"])).Equal(4)
+			})
+			g.It("Should error if a required file is missing", func() {
+				req := multipartRequest(map[string]string{"title": "cat"}, nil)
+				_, errors := Request(req, RuleBook{"upload": RB.Required().File()})
+				g.Assert(errors["upload"] != nil).IsTrue()
+			})
+		})
+	})
+}
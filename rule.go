@@ -1,10 +1,18 @@
 package validate
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
 	"reflect"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -17,64 +25,159 @@ const (
 	Bool
 	String
 	Time
+	File
+	Slice
+	Uint
+	Decimal
 )
 
-// Type of callbacks to be used in a Rule
+var typeNames = map[int]string{
+	Unknown: "unknown",
+	Int:     "int",
+	Float:   "float",
+	Number:  "number",
+	Bool:    "bool",
+	String:  "string",
+	Time:    "time",
+	File:    "file",
+	Slice:   "slice",
+	Uint:    "uint",
+	Decimal: "decimal",
+}
+
+func typeName(t int) string {
+	return typeNames[t]
+}
+
+// Type of callbacks to be used in a Rule. Prepares receive the raw input
+// (possibly nil) before type coercion, Customs receive the coerced value
+// after the built-in checks pass and Alters transform the value returned in
+// params. Neither Customs nor Alters are called with nil, and no callback
+// runs for a missing key.
 type AlterCallback func(value interface{}) interface{}
 type PrepareCallback func(value interface{}) interface{}
 type CustomCallback func(value interface{}) bool
 
+// CheckCallback is a richer Custom: it runs at the same stage but is given
+// the path of the value and the whole document being validated, and returns
+// the reason it failed
+type CheckCallback func(value interface{}, path string, doc *Document) error
+
+// Predicate decides whether a rule applies, given the raw input of the other
+// fields at its level of the RuleBook (keyed by RuleBook key)
+type Predicate func(siblings map[string]interface{}) bool
+
 // Rule encompasses a single validation rule for a parameter
 type Rule struct {
 	// validations
 	Key      string
+	Aliases  []string
 	Type     int
 	Required bool
+	Nullable bool
+	Default  interface{}
 	Regex    string
 	Message  string
 	Min      float64
 	Max      float64
+	Bits     int
 	Before   *time.Time
 	After    *time.Time
+	Layouts  []string
+	Epoch    time.Duration
 	In       []string
 
+	// times relative to the clock at validation
+	Past       bool
+	Future     bool
+	WithinLast time.Duration
+	WithinNext time.Duration
+	MinAge     int
+
+	// decimals
+	MinDecimal  *big.Rat
+	MaxDecimal  *big.Rat
+	Precision   int
+	Scale       int
+	DidSetScale bool
+
+	// other fields
+	Fields     []FieldComparison
+	Conditions []Predicate
+
+	// files
+	MaxSize      int64
+	MaxFiles     int
+	ContentTypes []string
+	Extensions   []string
+
+	// slices
+	Element  *Rule
+	MinItems int
+	MaxItems int
+	Unique   bool
+
 	// callbacks
 	Customs  []CustomCallback
+	Checks   []CheckCallback
 	Prepares []PrepareCallback
 	Alters   []AlterCallback
 
 	// using these since max / min get initialized to 0
-	DidSetMin bool
-	DidSetMax bool
+	DidSetMin      bool
+	DidSetMax      bool
+	DidSetMinItems bool
+	DidSetMaxItems bool
 }
 
-// Validates an input
+// Validates an input. Every error is a *ValidationError; those for the
+// elements of a slice have their index as Field, e.g. "[3]".
 func (rule *Rule) Process(input interface{}) (interface{}, []error) {
+	s := Validate(nil).session(&Document{})
+	output, _ := s.process(rule, input, "")
+	return output, s.flatten()
+}
+
+// checkDefault panics if the rule's Default doesn't pass the rule. Checks and
+// other-field comparisons need the document being validated, so they're
+// left to when the default is used.
+func (rule *Rule) checkDefault() {
+	if rule.Default == nil {
+		return
+	}
+	standalone := *rule
+	standalone.Checks, standalone.Fields = nil, nil
+	if _, errors := standalone.Process(rule.Default); len(errors) > 0 {
+		panic(fmt.Sprintf("Default(%v) fails its own rule: %v", rule.Default, errors))
+	}
+}
+
+// check validates an input against everything but the elements of a slice.
+// Relative times are checked against now.
+func (rule *Rule) check(input interface{}, now time.Time) (interface{}, []error) {
 	// ret values
 	var ok bool
 	var errors []error
 	var retInput = input
 
 	// type check
-	coercedInput, ok := rule.TypeOkFor(input)
-	if !ok { // failed type check
-		msg := fmt.Sprintf("Bad input type. Expecting type %v. Got: %v", rule.Type, reflect.TypeOf(retInput))
-		errors = append(errors, fmt.Errorf(msg))
+	coercedInput, err := rule.coerce(input)
+	if err != nil { // failed type check
+		errors = append(errors, err)
 
-		Log.Warning(msg)
+		Log.Warning(err.Error())
 	} else {
 		Log.Info("Input '%v' type is: %v", reflect.ValueOf(retInput), reflect.TypeOf(retInput))
 		retInput = coercedInput
 
 		// route return values by type
 		switch rule.Type {
-		case Int:
-			fallthrough
-		case Float:
-			fallthrough
-		case Number:
+		case Int, Uint, Float, Number:
 			ok, errors = rule.evalNumber(retInput)
 			break
+		case Decimal:
+			ok, errors = rule.evalDecimal(retInput.(*big.Rat))
+			break
 		case String:
 			ok, errors = rule.evalString(retInput.(string))
 			break
@@ -82,12 +185,18 @@ func (rule *Rule) Process(input interface{}) (interface{}, []error) {
 			ok, errors = rule.evalBoolean(retInput.(bool))
 			break
 		case Time:
-			ok, errors = rule.evalTime(retInput.(time.Time))
+			ok, errors = rule.evalTime(retInput.(time.Time), now)
+			break
+		case File:
+			ok, errors = rule.evalFiles(retInput.([]*multipart.FileHeader))
+			break
+		case Slice:
+			ok, errors = rule.evalSlice(retInput.([]interface{}))
 			break
 		}
 	}
 
-	Log.Debug("process(...) -> %v, %v", ok, errors)
+	Log.Debug("check(...) -> %v, %v", ok, errors)
 	return retInput, errors
 }
 
@@ -95,7 +204,7 @@ func (rule *Rule) Process(input interface{}) (interface{}, []error) {
   Type Eval Functions
 * * * * * * * * * * * * */
 
-func (rule *Rule) evalTime(val time.Time) (bool, []error) {
+func (rule *Rule) evalTime(val time.Time, now time.Time) (bool, []error) {
 	allOk := true
 	var errors []error
 
@@ -119,6 +228,10 @@ func (rule *Rule) evalTime(val time.Time) (bool, []error) {
 			Log.Debug("Given time (%v) < (%v) -- SUCCESS", val, *rule.Before)
 		}
 	}
+	if relative := rule.evalRelative(val, now); len(relative) > 0 {
+		errors = append(errors, relative...)
+		allOk = false
+	}
 
 	return allOk, errors
 }
@@ -157,27 +270,8 @@ func (rule *Rule) evalString(val string) (bool, []error) {
 }
 
 func (rule *Rule) evalNumber(val interface{}) (bool, []error) {
-	var ok bool
-	var errors []error
-
-	switch val.(type) {
-	case int32:
-		ok, errors = rule.evalInt(int(val.(int32)))
-	case int64:
-		ok, errors = rule.evalInt(int(val.(int64)))
-	case int:
-		ok, errors = rule.evalInt(val.(int))
-	case float32:
-		ok, errors = rule.evalFloat(val.(float64))
-	case float64:
-		ok, errors = rule.evalFloat(val.(float64))
-	}
-
-	return ok, errors
-}
-
-func (rule *Rule) evalInt(val int) (bool, []error) {
-	return rule.evalFloat(float64(val))
+	f, _ := toFloat(val)
+	return rule.evalFloat(f)
 }
 
 func (rule *Rule) evalFloat(val float64) (bool, []error) {
@@ -201,6 +295,56 @@ func (rule *Rule) evalFloat(val float64) (bool, []error) {
 	return allOk, errors
 }
 
+func (rule *Rule) evalFiles(files []*multipart.FileHeader) (bool, []error) {
+	allOk := true
+	var errors []error
+
+	if rule.MaxFiles > 0 && len(files) > rule.MaxFiles {
+		errors = append(errors, NewError(CodeMaxFiles, len(files), map[string]interface{}{"max": rule.MaxFiles},
+			"Got %v files (expecting at most %v)", len(files), rule.MaxFiles))
+		allOk = false
+	}
+	for _, file := range files {
+		if rule.MaxSize > 0 && file.Size > rule.MaxSize {
+			errors = append(errors, NewError(CodeMaxSize, file.Filename, map[string]interface{}{"max": rule.MaxSize, "size": file.Size},
+				"[%v] is %v bytes (expecting at most %v)", file.Filename, file.Size, rule.MaxSize))
+			allOk = false
+		}
+		if len(rule.Extensions) > 0 {
+			if ok, err := rule.evalExtension(file); !ok {
+				errors = append(errors, err)
+				allOk = false
+			}
+		}
+		if len(rule.ContentTypes) > 0 {
+			if ok, err := rule.evalContentType(file); !ok {
+				errors = append(errors, err)
+				allOk = false
+			}
+		}
+	}
+
+	return allOk, errors
+}
+
+func (rule *Rule) evalSlice(vals []interface{}) (bool, []error) {
+	allOk := true
+	var errors []error
+
+	if rule.DidSetMinItems && len(vals) < rule.MinItems {
+		errors = append(errors, NewError(CodeMinItems, len(vals), map[string]interface{}{"min": rule.MinItems},
+			"Got %v items (expecting at least %v)", len(vals), rule.MinItems))
+		allOk = false
+	}
+	if rule.DidSetMaxItems && len(vals) > rule.MaxItems {
+		errors = append(errors, NewError(CodeMaxItems, len(vals), map[string]interface{}{"max": rule.MaxItems},
+			"Got %v items (expecting at most %v)", len(vals), rule.MaxItems))
+		allOk = false
+	}
+
+	return allOk, errors
+}
+
 /* * * * * * * * * * * * *
   Rule Eval Functions
 * * * * * * * * * * * * */
@@ -210,7 +354,8 @@ func (rule *Rule) evalBefore(val time.Time) (bool, error) {
 
 	if val.After(*rule.Before) {
 		ok = false
-		err = fmt.Errorf("[%v] is AFTER %v (expecting it to be BEFORE)", val, rule.Before)
+		err = NewError(CodeBefore, val, map[string]interface{}{"before": *rule.Before},
+			"[%v] is AFTER %v (expecting it to be BEFORE)", val, *rule.Before)
 	}
 
 	return ok, err
@@ -222,7 +367,8 @@ func (rule *Rule) evalAfter(val time.Time) (bool, error) {
 
 	if val.Before(*rule.After) {
 		ok = false
-		err = fmt.Errorf("[%v] is BEFORE %v (expecting it to be AFTER)", val, rule.After)
+		err = NewError(CodeAfter, val, map[string]interface{}{"after": *rule.After},
+			"[%v] is BEFORE %v (expecting it to be AFTER)", val, *rule.After)
 	}
 
 	return ok, err
@@ -230,7 +376,7 @@ func (rule *Rule) evalAfter(val time.Time) (bool, error) {
 
 func (rule *Rule) evalIn(val string) (bool, error) {
 	ok := false
-	err := fmt.Errorf("[%v] not in %v", val, rule.In)
+	var err error = NewError(CodeIn, val, map[string]interface{}{"allowed": rule.In}, "[%v] not in %v", val, rule.In)
 
 	Log.Debug("Looking up [%v] in %v", val, rule.In)
 	for _, inVal := range rule.In {
@@ -255,7 +401,8 @@ func (rule *Rule) evalRegex(val string) (bool, error) {
 		// check regex
 		if k := expr.MatchString(val); !k {
 			Log.Debug("Failed regex")
-			err = fmt.Errorf("[%v] did not match regex [%v]", val, rule.Regex)
+			err = NewError(CodeRegex, val, map[string]interface{}{"regex": rule.Regex},
+				"[%v] did not match regex [%v]", val, rule.Regex)
 			ok = false
 		} else {
 			Log.Debug("Passed regex")
@@ -265,13 +412,71 @@ func (rule *Rule) evalRegex(val string) (bool, error) {
 	return ok, err
 }
 
+func (rule *Rule) evalExtension(file *multipart.FileHeader) (bool, error) {
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	for _, allowed := range rule.Extensions {
+		if ext == "."+strings.TrimPrefix(strings.ToLower(allowed), ".") {
+			return true, nil
+		}
+	}
+
+	return false, NewError(CodeExtension, file.Filename, map[string]interface{}{"allowed": rule.Extensions},
+		"[%v] extension not in %v", file.Filename, rule.Extensions)
+}
+
+// The content type is sniffed from the file itself; the header sent by the
+// client is never trusted
+func (rule *Rule) evalContentType(file *multipart.FileHeader) (bool, error) {
+	f, err := file.Open()
+	if err != nil {
+		return false, NewError(CodeFile, file.Filename, nil, "[%v] could not be opened: %v", file.Filename, err)
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := f.Read(head)
+	if err != nil && err != io.EOF {
+		return false, NewError(CodeFile, file.Filename, nil, "[%v] could not be read: %v", file.Filename, err)
+	}
+	detected := http.DetectContentType(head[:n])
+	mediaType := strings.TrimSpace(strings.Split(detected, ";")[0])
+
+	Log.Debug("Sniffed [%v] as %v", file.Filename, mediaType)
+	for _, allowed := range rule.ContentTypes {
+		if allowed == mediaType {
+			return true, nil
+		}
+		if strings.HasSuffix(allowed, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(allowed, "*")) {
+			return true, nil
+		}
+	}
+
+	return false, NewError(CodeContentType, file.Filename, map[string]interface{}{"allowed": rule.ContentTypes, "detected": mediaType},
+		"[%v] is %v (expecting one of %v)", file.Filename, mediaType, rule.ContentTypes)
+}
+
+// Uniqueness is checked after the elements are coerced, so "1" and 1 are
+// considered the same item
+func (rule *Rule) evalUnique(vals []interface{}) (bool, error) {
+	for i := range vals {
+		for j := 0; j < i; j++ {
+			if reflect.DeepEqual(vals[i], vals[j]) {
+				return false, NewError(CodeUnique, vals[i], map[string]interface{}{"first": j, "repeat": i},
+					"[%v] is repeated at [%v] and [%v]", vals[i], j, i)
+			}
+		}
+	}
+
+	return true, nil
+}
+
 func (rule *Rule) evalMin(val float64) (bool, error) {
 	ok := true
 	var err error
 
 	Log.Debug("Validating %v > %v...", val, rule.Min)
 	if val < rule.Min {
-		err = fmt.Errorf("Input(%v) < Minimum(%v)", val, rule.Min)
+		err = NewError(CodeMin, val, map[string]interface{}{"min": rule.Min}, "Input(%v) < Minimum(%v)", val, rule.Min)
 		ok = false
 	}
 
@@ -283,49 +488,49 @@ func (rule *Rule) evalMax(val float64) (bool, error) {
 	var err error
 
 	if val > rule.Max {
-		err = fmt.Errorf("Input(%v) > Maximum(%v)", val, rule.Min)
+		err = NewError(CodeMax, val, map[string]interface{}{"max": rule.Max}, "Input(%v) > Maximum(%v)", val, rule.Max)
 		ok = false
 	}
 
 	return ok, err
 }
 
+// coerce converts an input to the rule's type, explaining why it can't
+func (rule *Rule) coerce(input interface{}) (interface{}, error) {
+	switch rule.Type {
+	case Int, Uint, Float:
+		return rule.coerceNumber(input)
+	case Decimal:
+		return rule.coerceDecimal(input)
+	case Time:
+		return rule.coerceTime(input)
+	}
+
+	if output, ok := rule.TypeOkFor(input); ok {
+		return output, nil
+	}
+	return input, ConversionError(input, typeName(rule.Type))
+}
+
 func (rule *Rule) TypeOkFor(input interface{}) (interface{}, bool) {
 	var ok bool
 	var retInput interface{}
 
 	switch rule.Type {
-	case Int:
-		retInput, ok = input.(int)
-		if !ok {
-			retInput, ok = input.(int32)
-		}
-		if !ok {
-			retInput, ok = input.(int64)
-		}
-		break
-	case Float:
-		retInput, ok = input.(float64)
-		if !ok {
-			retInput, ok = input.(float32)
-		}
-		break
+	case Int, Uint, Float:
+		output, err := rule.coerceNumber(input)
+		return output, err == nil
+	case Decimal:
+		output, err := rule.coerceDecimal(input)
+		return output, err == nil
 	case Number:
-		retInput, ok = input.(float64)
-		if !ok {
-			retInput, ok = input.(float32)
-		}
-		if !ok {
-			retInput, ok = input.(int)
-		}
-		if !ok {
-			retInput, ok = input.(int64)
-		}
-		if !ok {
-			retInput, ok = input.(int32)
+		retInput = input
+		if n, isJSON := input.(json.Number); isJSON {
+			retInput, ok = convertStringToNumber(string(n))
+			break
 		}
-		if !ok {
-			Log.Warning("Could not convert %v OF TYPE %v to a number!! (tried float32, float64, int32, int64, int)", input, reflect.TypeOf(input))
+		if _, ok = toFloat(input); !ok {
+			Log.Warning("Could not convert %v OF TYPE %v to a number!!", input, reflect.TypeOf(input))
 		} else {
 			Log.Debug("Number -> %v", reflect.TypeOf(input))
 		}
@@ -337,9 +542,18 @@ func (rule *Rule) TypeOkFor(input interface{}) (interface{}, bool) {
 		retInput, ok = input.(bool)
 		break
 	case Time:
-		retInput, ok = input.(time.Time)
+		output, err := rule.coerceTime(input)
+		return output, err == nil
+	case Slice:
+		retInput, ok = toSlice(input)
+		break
+	case File:
+		retInput, ok = input.([]*multipart.FileHeader)
 		if !ok {
-			retInput, ok = input.(*time.Time)
+			var file *multipart.FileHeader
+			if file, ok = input.(*multipart.FileHeader); ok {
+				retInput = []*multipart.FileHeader{file}
+			}
 		}
 		break
 	}
@@ -376,35 +590,13 @@ func (rule *Rule) convertString(input string) interface{} {
 			converted = nil
 		}
 		break
-	case Time:
-		converted, err = time.Parse(input, input)
-		timeConvert, _ := converted.(time.Time)
-		Log.Debug("GOT TIME CONVERT --> %v", timeConvert)
-		if timeConvert.Year() == 0 && timeConvert.Month() == 1 && timeConvert.Day() == 1 {
-			// reject a zero'd date (1/1/0000)
-			converted = nil
-			ok = false
-		}
-		break
-	case Int:
-		fallthrough
-	case Float:
-		fallthrough
 	case Number:
-		var num interface{}
-		var t int
-		num, t, ok = convertStringToNumber(input)
+		var num float64
+		num, ok = convertStringToNumber(input)
 		if !ok {
 			Log.Error("Could not convert string '%v' to number!", input)
 		} else {
-			switch t {
-			case Int:
-				converted = num.(int)
-				break
-			case Float:
-				converted = num.(float64)
-				break
-			}
+			converted = num
 			Log.Debug("Converted %v to %v", input, converted)
 		}
 		break
@@ -414,26 +606,29 @@ func (rule *Rule) convertString(input string) interface{} {
 	return converted
 }
 
-func convertStringToNumber(val string) (interface{}, int, bool) {
-	Log.Debug("convertStringToNumber <- %v", val)
+// toSlice accepts any slice or array, e.g. []string from a form
+func toSlice(input interface{}) ([]interface{}, bool) {
+	if vals, ok := input.([]interface{}); ok {
+		return vals, true
+	}
 
-	var num interface{}
-	var t = 0
-	var ok = false
-
-	// first try to convert to float
-	for size := range []int{64, 32} { // try each size
-		float, err := strconv.ParseFloat(val, size)
-		if err == nil { // float success
-			num, t, ok = float, Float, true
-		} else { // try int
-			integer, err := strconv.ParseInt(val, 2, size)
-			if err == nil {
-				num, t, ok = integer, Int, true
-			}
-		}
+	v := reflect.ValueOf(input)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, false
+	}
+	vals := make([]interface{}, v.Len())
+	for i := range vals {
+		vals[i] = v.Index(i).Interface()
 	}
+	return vals, true
+}
+
+func convertStringToNumber(val string) (float64, bool) {
+	Log.Debug("convertStringToNumber <- %v", val)
+
+	num, err := strconv.ParseFloat(val, 64)
+	ok := err == nil && !math.IsNaN(num) && !math.IsInf(num, 0)
 
-	Log.Debug("convertStringToNumber -> %v, %v, %v", num, t, ok)
-	return num, t, ok
+	Log.Debug("convertStringToNumber -> %v, %v", num, ok)
+	return num, ok
 }
@@ -1,6 +1,7 @@
 package validate
 
 import (
+	"fmt"
 	"github.com/lann/builder"
 	"reflect"
 	"time"
@@ -20,7 +21,7 @@ func (rb ruleBuilder) updateTypeAccordingTo(val interface{}) ruleBuilder {
 	switch t.Kind() {
 	case reflect.Bool:
 		Log.Debug("Type to boolean")
-		rb = rb.Bool()
+		rb = rb.setType(Bool)
 		break
 	case reflect.Int:
 		fallthrough
@@ -51,28 +52,40 @@ func (rb ruleBuilder) updateTypeAccordingTo(val interface{}) ruleBuilder {
 	case reflect.Complex64:
 		fallthrough
 	case reflect.Complex128:
+		if is := rb.Build().Type; is == Int || is == Uint || is == Float || is == Decimal {
+			break // already a more specific number
+		}
 		Log.Debug("Type to number")
-		rb = rb.Number()
+		rb = rb.setType(Number)
 		break
 	case reflect.String:
 		Log.Debug("Type to string")
-		rb = rb.String()
+		rb = rb.setType(String)
 		break
 	case reflect.UnsafePointer:
 		fallthrough
 	case reflect.Ptr:
 		if _, ok := val.(*time.Time); ok {
 			Log.Debug("Type to time")
-			rb = rb.Time()
+			rb = rb.setType(Time)
 		}
 		break
-	case reflect.Struct:
 	case reflect.Array:
+		fallthrough
+	case reflect.Slice:
+		Log.Debug("Type to slice")
+		rb = rb.setType(Slice)
+		break
+	case reflect.Struct:
+		if _, ok := val.(time.Time); ok {
+			Log.Debug("Type to time")
+			rb = rb.setType(Time)
+		}
+		break
 	case reflect.Chan:
 	case reflect.Func:
 	case reflect.Interface:
 	case reflect.Map:
-	case reflect.Slice:
 	default:
 		panic("Do not understand this type: " + reflect.TypeOf(val).Kind().String())
 	}
@@ -80,31 +93,120 @@ func (rb ruleBuilder) updateTypeAccordingTo(val interface{}) ruleBuilder {
 	return rb
 }
 
+func (rb ruleBuilder) setType(is int) ruleBuilder {
+	return builder.Set(rb, "Type", is).(ruleBuilder)
+}
+
+// checked panics if the rule's default doesn't pass the rule as built so far
+func (rb ruleBuilder) checked() ruleBuilder {
+	rule := rb.Build()
+	rule.checkDefault()
+	return rb
+}
+
 // required / optional
 func (rb ruleBuilder) Required() ruleBuilder {
 	return builder.Set(rb, "Required", true).(ruleBuilder)
 }
+func (rb ruleBuilder) Nullable() ruleBuilder {
+	return builder.Set(rb, "Nullable", true).(ruleBuilder)
+}
 
-// key
+// default is used when the key is missing and the rule isn't Required. A
+// rule without a type yet takes the default's. The default is run through
+// the rule here and again by every builder that changes the type or adds a
+// constraint after it, panicking as soon as it fails.
+func (rb ruleBuilder) Default(val interface{}) ruleBuilder {
+	if rule := rb.Build(); rule.Type == Unknown {
+		rb = rb.updateTypeAccordingTo(val)
+	}
+	return builder.Set(rb, "Default", val).(ruleBuilder).checked()
+}
+
+// key is the input key read in place of the RuleBook key; params and errors
+// still use the RuleBook key. Aliases are further input keys accepted for it.
 func (rb ruleBuilder) Key(key string) ruleBuilder {
 	return builder.Set(rb, "Key", key).(ruleBuilder)
 }
+func (rb ruleBuilder) Alias(names ...string) ruleBuilder {
+	for _, name := range names {
+		rb = builder.Append(rb, "Aliases", name).(ruleBuilder)
+	}
+	return rb
+}
 
 // type
 func (rb ruleBuilder) Type(is string) ruleBuilder {
-	return builder.Set(rb, "Type", is).(ruleBuilder)
+	return builder.Set(rb, "Type", is).(ruleBuilder).checked()
 }
 func (rb ruleBuilder) String() ruleBuilder {
-	return builder.Set(rb, "Type", String).(ruleBuilder)
+	return rb.setType(String).checked()
 }
 func (rb ruleBuilder) Number() ruleBuilder {
-	return builder.Set(rb, "Type", Number).(ruleBuilder)
+	return rb.setType(Number).checked()
+}
+func (rb ruleBuilder) Int() ruleBuilder {
+	return rb.setType(Int).checked()
+}
+func (rb ruleBuilder) Uint() ruleBuilder {
+	return rb.setType(Uint).checked()
+}
+func (rb ruleBuilder) Float() ruleBuilder {
+	return rb.setType(Float).checked()
+}
+func (rb ruleBuilder) Decimal() ruleBuilder {
+	return rb.setType(Decimal).checked()
 }
 func (rb ruleBuilder) Bool() ruleBuilder {
-	return builder.Set(rb, "Type", Bool).(ruleBuilder)
+	return rb.setType(Bool).checked()
 }
 func (rb ruleBuilder) Time() ruleBuilder {
-	return builder.Set(rb, "Type", Time).(ruleBuilder)
+	return rb.setType(Time).checked()
+}
+func (rb ruleBuilder) Date() ruleBuilder {
+	return rb.Layout(DateLayouts...)
+}
+func (rb ruleBuilder) TimeOfDay() ruleBuilder {
+	return rb.Layout(TimeOfDayLayouts...)
+}
+func (rb ruleBuilder) File() ruleBuilder {
+	return rb.setType(File).checked()
+}
+func (rb ruleBuilder) Slice() ruleBuilder {
+	return rb.setType(Slice).checked()
+}
+
+// bits limits an Int or Uint to 8, 16, 32 or 64 bits and a Float to 32 or
+// 64; values that don't fit are reported as overflowing. The value is
+// returned as an int64, uint64 or float64 regardless.
+func (rb ruleBuilder) Bits(bits int) ruleBuilder {
+	switch bits {
+	case 8, 16, 32, 64:
+		if bits < 32 && rb.Build().Type == Float {
+			panic(fmt.Sprintf("Bits(%v) isn't a float size", bits))
+		}
+	default:
+		panic(fmt.Sprintf("Bits(%v) isn't a number size", bits))
+	}
+	return builder.Set(rb, "Bits", bits).(ruleBuilder).checked()
+}
+
+// decimal limits are given as strings so they're exact, e.g. "0.01"
+func (rb ruleBuilder) MinDecimal(min string) ruleBuilder {
+	return builder.Set(rb.setType(Decimal), "MinDecimal", parseDecimal(min)).(ruleBuilder).checked()
+}
+func (rb ruleBuilder) MaxDecimal(max string) ruleBuilder {
+	return builder.Set(rb.setType(Decimal), "MaxDecimal", parseDecimal(max)).(ruleBuilder).checked()
+}
+
+// precision is the most digits a decimal may have in all, scale the most
+// after its decimal point; DECIMAL(10, 2) is Precision(10).Scale(2)
+func (rb ruleBuilder) Precision(digits int) ruleBuilder {
+	return builder.Set(rb.setType(Decimal), "Precision", digits).(ruleBuilder).checked()
+}
+func (rb ruleBuilder) Scale(digits int) ruleBuilder {
+	rb = builder.Set(rb.setType(Decimal), "Scale", digits).(ruleBuilder)
+	return builder.Set(rb, "DidSetScale", true).(ruleBuilder).checked()
 }
 
 // message
@@ -116,7 +218,7 @@ func (rb ruleBuilder) Message(msg string) ruleBuilder {
 func (rb ruleBuilder) Regex(regex string) ruleBuilder {
 	rb = builder.Set(rb, "Regex", regex).(ruleBuilder)
 	rb = rb.updateTypeAccordingTo(regex)
-	return rb
+	return rb.checked()
 }
 
 // min, max, equals, between
@@ -124,20 +226,20 @@ func (rb ruleBuilder) Min(min float64) ruleBuilder {
 	rb = builder.Set(rb, "Min", min).(ruleBuilder)
 	rb = builder.Set(rb, "DidSetMin", true).(ruleBuilder)
 	rb = rb.updateTypeAccordingTo(min)
-	return rb
+	return rb.checked()
 }
 
 func (rb ruleBuilder) Max(max float64) ruleBuilder {
 	rb = builder.Set(rb, "Max", max).(ruleBuilder)
 	rb = builder.Set(rb, "DidSetMax", true).(ruleBuilder)
 	rb = rb.updateTypeAccordingTo(max)
-	return rb
+	return rb.checked()
 }
 
 func (rb ruleBuilder) In(val []string) ruleBuilder {
 	rb = builder.Set(rb, "In", val).(ruleBuilder)
 	rb = rb.updateTypeAccordingTo("a string")
-	return rb
+	return rb.checked()
 }
 
 func (rb ruleBuilder) Between(min interface{}, max interface{}) ruleBuilder {
@@ -150,7 +252,7 @@ func (rb ruleBuilder) Between(min interface{}, max interface{}) ruleBuilder {
 	builder.Set(rb, "Min", min)
 	builder.Set(rb, "Max", max)
 
-	return rb
+	return rb.checked()
 }
 
 // time
@@ -158,29 +260,128 @@ func (rb ruleBuilder) After(t time.Time) ruleBuilder {
 	ptr := &t
 	rb = builder.Set(rb, "After", ptr).(ruleBuilder)
 	rb = rb.updateTypeAccordingTo(ptr)
-	return rb
+	return rb.checked()
 }
 func (rb ruleBuilder) Before(t time.Time) ruleBuilder {
 	ptr := &t
 	rb = builder.Set(rb, "Before", ptr).(ruleBuilder)
 	rb = rb.updateTypeAccordingTo(ptr)
-	return rb
+	return rb.checked()
+}
+
+// other fields, compared by their RuleBook key at the same level
+func (rb ruleBuilder) EqualsField(field string) ruleBuilder {
+	return builder.Append(rb, "Fields", FieldComparison{field, CodeEqualsField}).(ruleBuilder)
+}
+func (rb ruleBuilder) GreaterThanField(field string) ruleBuilder {
+	return builder.Append(rb, "Fields", FieldComparison{field, CodeGreaterThanField}).(ruleBuilder)
+}
+func (rb ruleBuilder) GreaterThanOrEqualField(field string) ruleBuilder {
+	return builder.Append(rb, "Fields", FieldComparison{field, CodeGreaterOrEqualField}).(ruleBuilder)
+}
+func (rb ruleBuilder) AfterField(field string) ruleBuilder {
+	return builder.Append(rb.setType(Time), "Fields", FieldComparison{field, CodeAfterField}).(ruleBuilder).checked()
+}
+func (rb ruleBuilder) BeforeField(field string) ruleBuilder {
+	return builder.Append(rb.setType(Time), "Fields", FieldComparison{field, CodeBeforeField}).(ruleBuilder).checked()
+}
+
+// conditions; a rule whose conditions don't all hold is skipped as if it
+// weren't in the RuleBook
+func (rb ruleBuilder) RequiredIf(field string, values ...interface{}) ruleBuilder {
+	return When(func(siblings map[string]interface{}) bool {
+		return matches(siblings, field, values)
+	}, rb.Required())
+}
+func (rb ruleBuilder) RequiredUnless(field string, values ...interface{}) ruleBuilder {
+	return When(func(siblings map[string]interface{}) bool {
+		return !matches(siblings, field, values)
+	}, rb.Required())
+}
+func When(predicate Predicate, rules ruleBuilder) ruleBuilder {
+	return builder.Append(rules, "Conditions", predicate).(ruleBuilder)
+}
+
+// layouts replace DefaultLayouts for parsing strings, tried in order
+func (rb ruleBuilder) Layout(layouts ...string) ruleBuilder {
+	return builder.Set(rb.setType(Time), "Layouts", layouts).(ruleBuilder).checked()
+}
+
+// relative times are checked against the clock when validating, so a
+// long-lived RuleBook doesn't go stale the way Before(time.Now()) would
+func (rb ruleBuilder) Past() ruleBuilder {
+	return builder.Set(rb.setType(Time), "Past", true).(ruleBuilder).checked()
+}
+func (rb ruleBuilder) Future() ruleBuilder {
+	return builder.Set(rb.setType(Time), "Future", true).(ruleBuilder).checked()
+}
+func (rb ruleBuilder) WithinLast(d time.Duration) ruleBuilder {
+	return builder.Set(rb.setType(Time), "WithinLast", d).(ruleBuilder).checked()
+}
+func (rb ruleBuilder) WithinNext(d time.Duration) ruleBuilder {
+	return builder.Set(rb.setType(Time), "WithinNext", d).(ruleBuilder).checked()
+}
+func (rb ruleBuilder) MinAge(years int) ruleBuilder {
+	return builder.Set(rb.setType(Time), "MinAge", years).(ruleBuilder).checked()
+}
+
+// epoch reads numbers and numeric strings as Unix timestamps in a unit of
+// time.Second, time.Millisecond, time.Microsecond or time.Nanosecond
+func (rb ruleBuilder) Epoch(unit time.Duration) ruleBuilder {
+	if _, ok := epochUnits[unit]; !ok {
+		panic(fmt.Sprintf("Epoch(%v) isn't a timestamp unit", unit))
+	}
+	return builder.Set(rb.setType(Time), "Epoch", unit).(ruleBuilder).checked()
+}
+
+// slice
+func (rb ruleBuilder) Each(element ruleBuilder) ruleBuilder {
+	rule := element.Build()
+	return builder.Set(rb.setType(Slice), "Element", &rule).(ruleBuilder).checked()
+}
+func (rb ruleBuilder) MinItems(min int) ruleBuilder {
+	rb = builder.Set(rb.setType(Slice), "MinItems", min).(ruleBuilder)
+	return builder.Set(rb, "DidSetMinItems", true).(ruleBuilder).checked()
+}
+func (rb ruleBuilder) MaxItems(max int) ruleBuilder {
+	rb = builder.Set(rb.setType(Slice), "MaxItems", max).(ruleBuilder)
+	return builder.Set(rb, "DidSetMaxItems", true).(ruleBuilder).checked()
+}
+func (rb ruleBuilder) Unique() ruleBuilder {
+	return builder.Set(rb.setType(Slice), "Unique", true).(ruleBuilder).checked()
+}
+
+// file
+func (rb ruleBuilder) MaxSize(bytes int64) ruleBuilder {
+	return builder.Set(rb.setType(File), "MaxSize", bytes).(ruleBuilder).checked()
+}
+func (rb ruleBuilder) MaxFiles(count int) ruleBuilder {
+	return builder.Set(rb.setType(File), "MaxFiles", count).(ruleBuilder).checked()
+}
+func (rb ruleBuilder) ContentTypes(types ...string) ruleBuilder {
+	return builder.Set(rb.setType(File), "ContentTypes", types).(ruleBuilder).checked()
+}
+func (rb ruleBuilder) Extensions(exts ...string) ruleBuilder {
+	return builder.Set(rb.setType(File), "Extensions", exts).(ruleBuilder).checked()
 }
 
 // callback
 func (rb ruleBuilder) Custom(cb CustomCallback) ruleBuilder {
-	return builder.Append(rb, "Customs", cb).(ruleBuilder)
+	return builder.Append(rb, "Customs", cb).(ruleBuilder).checked()
+}
+func (rb ruleBuilder) Check(cb CheckCallback) ruleBuilder {
+	return builder.Append(rb, "Checks", cb).(ruleBuilder)
 }
 func (rb ruleBuilder) Alter(cb AlterCallback) ruleBuilder {
 	return builder.Append(rb, "Alters", cb).(ruleBuilder)
 }
 func (rb ruleBuilder) Prepare(cb PrepareCallback) ruleBuilder {
-	return builder.Append(rb, "Prepares", cb).(ruleBuilder)
+	return builder.Append(rb, "Prepares", cb).(ruleBuilder).checked()
 }
 
 // custom
 func (rb ruleBuilder) Email() ruleBuilder {
-	return builder.Set(rb, "Regex", `(?i)[A-Z0-9._%+-]+@(?:[A-Z0-9-]+\.)+[A-Z]{2,6}`).(ruleBuilder)
+	return rb.Regex(`(?i)[A-Z0-9._%+-]+@(?:[A-Z0-9-]+\.)+[A-Z]{2,6}`)
 }
 
 var RuleBuilder = builder.Register(ruleBuilder{}, Rule{}).(ruleBuilder)
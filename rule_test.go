@@ -1,9 +1,12 @@
 package validate_test
 
 import (
+	"encoding/json"
 	. "github.com/franela/goblin"
 	. "github.com/joslinm/validate"
+	"math/big"
 	_ "reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -38,7 +41,7 @@ func TestRules(t *testing.T) {
 				}
 			})
 			g.It("Should convert a time string", func() {
-				rule := RB.Time().Build()
+				rule := RB.Layout("2006-Jan-02").Build()
 				time_, _ := time.Parse("2006-Jan-02", "2006-Jan-02")
 				output, ok := rule.TypeOkFor("2006-Jan-02")
 				g.Assert(output).Equal(time_)
@@ -95,6 +98,137 @@ func TestRules(t *testing.T) {
 					})
 				})
 
+				/* Int, Uint, Float */
+				g.Describe("Int, Uint, Float", func() {
+					code := func(errors []error) string {
+						return errors[0].(*ValidationError).Code
+					}
+
+					g.It("Should coerce every numeric kind", func() {
+						rule := RB.Int().Build()
+						for _, val := range []interface{}{int8(5), uint16(5), float32(5), 5.0, "5", int64(5)} {
+							output, errors := rule.Process(val)
+							g.Assert(len(errors)).Equal(0)
+							g.Assert(output).Equal(int64(5))
+						}
+						rule = RB.Float().Build()
+						output, _ := rule.Process(float32(1.5))
+						g.Assert(output).Equal(1.5)
+						rule = RB.Uint().Build()
+						output, _ = rule.Process("7")
+						g.Assert(output).Equal(uint64(7))
+					})
+					g.It("Should accept float32 and int64 for Number", func() {
+						rule := RB.Number().Max(1).Build()
+						_, errors := rule.Process(float32(1.5))
+						g.Assert(len(errors)).Equal(1)
+						_, errors = rule.Process(int64(1) << 40)
+						g.Assert(len(errors)).Equal(1)
+					})
+					g.It("Should reject fractional input for integer rules", func() {
+						rule := RB.Int().Build()
+						for _, val := range []interface{}{5.5, "5.5", float32(0.25)} {
+							_, errors := rule.Process(val)
+							g.Assert(code(errors)).Equal(CodeInteger)
+						}
+					})
+					g.It("Should report values that don't fit in Bits", func() {
+						rule := RB.Int().Bits(8).Build()
+						_, errors := rule.Process(128)
+						g.Assert(code(errors)).Equal(CodeOverflow)
+						g.Assert(errors[0].Error()).Equal("value doesn't fit in int8")
+						_, errors = rule.Process(-128)
+						g.Assert(len(errors)).Equal(0)
+						rule = RB.Uint().Build()
+						_, errors = rule.Process(-1)
+						g.Assert(code(errors)).Equal(CodeOverflow)
+						rule = RB.Uint().Bits(16).Build()
+						_, errors = rule.Process("65536")
+						g.Assert(code(errors)).Equal(CodeOverflow)
+						rule = RB.Int().Build()
+						_, errors = rule.Process("9223372036854775808")
+						g.Assert(code(errors)).Equal(CodeOverflow)
+						_, errors = rule.Process(uint64(1) << 63)
+						g.Assert(code(errors)).Equal(CodeOverflow)
+						rule = RB.Float().Bits(32).Build()
+						_, errors = rule.Process(1e39)
+						g.Assert(code(errors)).Equal(CodeOverflow)
+					})
+					g.It("Should keep its type when given a min or max", func() {
+						rule := RB.Int().Min(1).Max(10).Build()
+						g.Assert(rule.Type).Equal(Int)
+						_, errors := rule.Process(11)
+						g.Assert(code(errors)).Equal(CodeMax)
+					})
+					g.It("Should reject non-numeric input", func() {
+						rule := RB.Float().Build()
+						for _, val := range []interface{}{"five", "NaN", true} {
+							_, errors := rule.Process(val)
+							g.Assert(code(errors)).Equal(CodeType)
+						}
+					})
+				})
+
+				/* Decimal */
+				g.Describe("Decimal", func() {
+					g.It("Should return the exact value", func() {
+						rule := RB.Decimal().Build()
+						for _, val := range []interface{}{"0.1", json.Number("0.1"), 0.1, big.NewRat(1, 10)} {
+							output, errors := rule.Process(val)
+							g.Assert(len(errors)).Equal(0)
+							g.Assert(output.(*big.Rat).Cmp(big.NewRat(1, 10))).Equal(0)
+						}
+						output, _ := rule.Process("123456789012345678901234567890.01")
+						g.Assert(output.(*big.Rat).FloatString(2)).Equal("123456789012345678901234567890.01")
+					})
+					g.It("Should compare exactly against MinDecimal and MaxDecimal", func() {
+						rule := RB.MinDecimal("0.01").MaxDecimal("99999999999999999.99").Build()
+						_, errors := rule.Process("0.009")
+						g.Assert(errors[0].Error()).Equal("value must be at least 0.01")
+						_, errors = rule.Process("99999999999999999.991")
+						g.Assert(errors[0].(*ValidationError).Code).Equal(CodeMax)
+						_, errors = rule.Process("99999999999999999.99")
+						g.Assert(len(errors)).Equal(0)
+					})
+					g.It("Should check precision and scale", func() {
+						rule := RB.Decimal().Precision(5).Scale(2).Build()
+						for _, val := range []string{"123.45", "0.05", "-999.9", "1.50"} {
+							_, errors := rule.Process(val)
+							g.Assert(len(errors)).Equal(0)
+						}
+						_, errors := rule.Process("1.234")
+						g.Assert(errors[0].(*ValidationError).Code).Equal(CodeScale)
+						_, errors = rule.Process("1234.5")
+						g.Assert(errors[0].(*ValidationError).Code).Equal(CodePrecision)
+						_, errors = rule.Process(big.NewRat(1, 3))
+						g.Assert(len(errors)).Equal(2)
+					})
+					g.It("Should reject anything but plain decimals", func() {
+						rule := RB.Decimal().Build()
+						for _, val := range []interface{}{"1/3", "0x10", "1e99999", "abc", true} {
+							_, errors := rule.Process(val)
+							g.Assert(errors[0].(*ValidationError).Code).Equal(CodeType)
+						}
+					})
+					g.It("Should refuse literals longer than MaxDecimalLength without parsing them", func() {
+						long := strings.Repeat("9", 1000000)
+						start := time.Now()
+						rule := RB.Decimal().Build()
+						_, errors := rule.Process(long)
+						g.Assert(errors[0].(*ValidationError).Code).Equal(CodeType)
+						_, errors = rule.Process(json.Number(long))
+						g.Assert(errors[0].(*ValidationError).Code).Equal(CodeType)
+						rule = RB.Epoch(time.Second).Build()
+						_, errors = rule.Process(long)
+						g.Assert(errors[0].(*ValidationError).Code).Equal(CodeType)
+						g.Assert(time.Since(start) < time.Second).IsTrue()
+
+						rule = RB.Decimal().Build()
+						_, errors = rule.Process(strings.Repeat("9", MaxDecimalLength))
+						g.Assert(len(errors)).Equal(0)
+					})
+				})
+
 				/* String */
 				g.Describe("String", func() {
 					// :]
@@ -190,11 +324,138 @@ func TestRules(t *testing.T) {
 						_, errors := rule.Process(time.Now())
 						g.Assert(len(errors)).Equal(1)
 					})
+
+					// layouts
+					g.It("should parse RFC 3339 strings by default", func() {
+						rule := RB.Time().Build()
+						output, errors := rule.Process("2024-03-01T10:30:00Z")
+						g.Assert(len(errors)).Equal(0)
+						g.Assert(output).Equal(time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC))
+					})
+					g.It("should try each layout in order and list them on failure", func() {
+						rule := RB.Layout("2006-01-02", "01/02/2006").Build()
+						output, _ := rule.Process("03/01/2024")
+						g.Assert(output).Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+						_, errors := rule.Process("1 March 2024")
+						g.Assert(errors[0].(*ValidationError).Code).Equal(CodeLayout)
+						g.Assert(errors[0].Error()).Equal("value must be a time like 2006-01-02, 01/02/2006")
+					})
+					g.It("should parse dates and times of day", func() {
+						date := RB.Date().Build()
+						output, _ := date.Process("2024-03-01")
+						g.Assert(output).Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+						clock := RB.TimeOfDay().Build()
+						output, _ = clock.Process("09:15")
+						g.Assert(output).Equal(time.Date(0, 1, 1, 9, 15, 0, 0, time.UTC))
+						_, errors := clock.Process("2024-03-01")
+						g.Assert(len(errors)).Equal(1)
+					})
+					g.It("should read epoch timestamps in the given unit", func() {
+						want := time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC)
+						seconds := RB.Epoch(time.Second).Build()
+						for _, val := range []interface{}{1700000000, "1700000000", json.Number("1700000000"), 1700000000.0} {
+							output, errors := seconds.Process(val)
+							g.Assert(len(errors)).Equal(0)
+							g.Assert(output).Equal(want)
+						}
+						millis := RB.Epoch(time.Millisecond).Build()
+						output, _ := millis.Process(int64(1700000000250))
+						g.Assert(output).Equal(want.Add(250 * time.Millisecond))
+						output, _ = seconds.Process("1700000000.5")
+						g.Assert(output).Equal(want.Add(500 * time.Millisecond))
+					})
+					g.It("should refuse epoch timestamps outside the plausible years", func() {
+						seconds := RB.Epoch(time.Second).Build()
+						_, errors := seconds.Process(int64(1700000000000))
+						g.Assert(errors[0].(*ValidationError).Code).Equal(CodeEpoch)
+						g.Assert(errors[0].Error()).Equal("value must be a timestamp in seconds between 1900 and 2200")
+						_, errors = seconds.Process("1e300")
+						g.Assert(errors[0].(*ValidationError).Code).Equal(CodeEpoch)
+					})
+					g.It("should still parse layouts with an epoch unit", func() {
+						rule := RB.Epoch(time.Second).Build()
+						output, _ := rule.Process("2023-11-14T22:13:20Z")
+						g.Assert(output).Equal(time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC))
+						rule = RB.Time().Build()
+						_, errors := rule.Process(1700000000)
+						g.Assert(errors[0].(*ValidationError).Code).Equal(CodeType)
+					})
+					g.It("should accept a *time.Time", func() {
+						now := time.Now()
+						rule := RB.Time().Build()
+						output, errors := rule.Process(&now)
+						g.Assert(len(errors)).Equal(0)
+						g.Assert(output).Equal(now)
+					})
 				})
 			})
 
 		})
 
+		g.Describe("Callbacks", func() {
+			g.It("Should run Prepares before type coercion", func() {
+				rule := RB.Bool().Prepare(func(val interface{}) interface{} {
+					if val == "yes" {
+						return "true"
+					}
+					return val
+				}).Build()
+				input, errors := rule.Process("yes")
+				g.Assert(len(errors)).Equal(0)
+				g.Assert(input).Equal(true)
+			})
+			g.It("Should let a Prepare replace a null value", func() {
+				rule := RB.String().Prepare(func(val interface{}) interface{} {
+					if val == nil {
+						return ""
+					}
+					return val
+				}).Build()
+				input, errors := rule.Process(nil)
+				g.Assert(len(errors)).Equal(0)
+				g.Assert(input).Equal("")
+			})
+			g.It("Should report a failed Custom as an error", func() {
+				rule := RB.String().Custom(func(val interface{}) bool {
+					return val.(string) != "bad"
+				}).Build()
+				_, errors := rule.Process("bad")
+				g.Assert(len(errors)).Equal(1)
+				_, errors = rule.Process("good")
+				g.Assert(len(errors)).Equal(0)
+			})
+			g.It("Should only run Customs once the built-in checks pass", func() {
+				called := false
+				rule := RB.Min(5).Custom(func(val interface{}) bool {
+					called = true
+					return true
+				}).Build()
+				_, errors := rule.Process(1)
+				g.Assert(len(errors)).Equal(1)
+				g.Assert(called).IsFalse()
+			})
+			g.It("Should return the Altered value", func() {
+				rule := RB.Regex("^[a-z]+$").Alter(func(val interface{}) interface{} {
+					return val.(string) + "!"
+				}).Alter(func(val interface{}) interface{} {
+					return val.(string) + "?"
+				}).Build()
+				input, errors := rule.Process("hi")
+				g.Assert(len(errors)).Equal(0)
+				g.Assert(input).Equal("hi!?")
+			})
+			g.It("Should skip Customs and Alters for a nullable null", func() {
+				rule := RB.String().Nullable().Custom(func(val interface{}) bool {
+					return false
+				}).Alter(func(val interface{}) interface{} {
+					return "altered"
+				}).Build()
+				input, errors := rule.Process(nil)
+				g.Assert(len(errors)).Equal(0)
+				g.Assert(input == nil).IsTrue()
+			})
+		})
+
 		// Integration Tests
 		/* Types
 		/*************
@@ -0,0 +1,164 @@
+package validate
+
+import (
+	"mime/multipart"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+var fileType = reflect.TypeOf(&multipart.FileHeader{})
+
+// RuleBookFor creates a RuleBook with a rule for every exported field of a
+// struct whose type is recognized. Keys follow the field's json tag when it
+// has one. Nested structs get a nested RuleBook and slices an Each() rule.
+// A field referring back to a struct type already being walked is skipped,
+// or left a plain Slice() rule if it's a slice.
+func RuleBookFor(v interface{}, required bool) RuleBook {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		panic("RuleBookFor(...) expects a struct. Got: " + reflect.TypeOf(v).String())
+	}
+
+	return ruleBookFor(t, required, map[reflect.Type]bool{})
+}
+
+// compiling holds the struct types being walked, to break cycles
+func ruleBookFor(t reflect.Type, required bool, compiling map[reflect.Type]bool) RuleBook {
+	compiling[t] = true
+	defer delete(compiling, t)
+
+	book := RuleBook{}
+	for _, field := range fieldsOf(t) {
+		if rule, ok := ruleFor(field.Type, required, compiling); ok {
+			book[field.key] = rule
+		} else {
+			Log.Debug("RuleBookFor(...) skipping %v: unrecognized or recursive type %v", field.Name, field.Type)
+		}
+	}
+
+	return book
+}
+
+// ruleFor maps a Go type to either a ruleBuilder or a nested RuleBook
+func ruleFor(t reflect.Type, required bool, compiling map[reflect.Type]bool) (interface{}, bool) {
+	rb := RuleBuilder
+	if required {
+		rb = rb.Required()
+	}
+
+	if t == fileType {
+		return rb.File(), true
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return rb.Time(), true
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return rb.String(), true
+	case reflect.Bool:
+		return rb.Bool(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rb.Int().Bits(t.Bits()), true
+	case reflect.Float32, reflect.Float64:
+		return rb.Float().Bits(t.Bits()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rb.Uint().Bits(t.Bits()), true
+	case reflect.Struct:
+		if compiling[t] {
+			return nil, false
+		}
+		return ruleBookFor(t, required, compiling), true
+	case reflect.Slice, reflect.Array:
+		if t.Elem() == fileType {
+			return rb.File(), true
+		}
+		if element, ok := ruleFor(t.Elem(), false, compiling); ok {
+			if elemRule, isRule := element.(ruleBuilder); isRule {
+				return rb.Each(elemRule), true
+			}
+		}
+		return rb.Slice(), true
+	}
+
+	return nil, false
+}
+
+/* * * * * * * * * * * * *
+  Helper Functions
+* * * * * * * * * * * * */
+
+type structField struct {
+	reflect.StructField
+	key   string
+	index []int
+}
+
+// fieldsOf lists the exported fields of a struct the way encoding/json sees
+// them: renamed by json tags, "-" skipped and untagged embedded structs
+// flattened into their parent
+func fieldsOf(t reflect.Type) []structField {
+	return embeddedFields(t, map[reflect.Type]bool{})
+}
+
+// embeddedFields is fieldsOf(...) keeping track of the embedded types on the
+// path, so a struct embedding itself (type T struct{ *T }) isn't flattened
+// again
+func embeddedFields(t reflect.Type, visiting map[reflect.Type]bool) []structField {
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key, ok := fieldKey(field)
+		if !ok {
+			continue
+		}
+
+		ft := field.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if field.Anonymous && ft.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			if visiting[ft] {
+				continue
+			}
+			for _, embedded := range embeddedFields(ft, visiting) {
+				embedded.index = append([]int{i}, embedded.index...)
+				fields = append(fields, embedded)
+			}
+			continue
+		}
+		if field.PkgPath != "" { // unexported embedded non-struct
+			continue
+		}
+
+		fields = append(fields, structField{StructField: field, key: key, index: []int{i}})
+	}
+
+	return fields
+}
+
+func fieldKey(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" && !field.Anonymous { // unexported
+		return "", false
+	}
+
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name, true
+	}
+	return field.Name, true
+}
@@ -0,0 +1,201 @@
+package validate_test
+
+import (
+	. "github.com/franela/goblin"
+	. "github.com/joslinm/validate"
+	"testing"
+	"time"
+)
+
+type address struct {
+	City string `json:"city"`
+	Zip  string `json:"zip"`
+}
+
+type Audit struct {
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type userParams struct {
+	Audit
+	FirstName string   `json:"first_name"`
+	Email     string   `json:"email,omitempty"`
+	Age       int      `json:"age"`
+	Score     float64  `json:"score"`
+	Admin     bool     `json:"admin"`
+	Tags      []string `json:"tags"`
+	Address   address  `json:"address"`
+	Nickname  *string
+	Secret    string `json:"-"`
+	internal  string
+}
+
+type signup struct {
+	Name    string     `json:"name" validate:"required,regex=^[a-z]{2,8}$"`
+	Email   string     `json:"email" validate:"required,email"`
+	Age     int        `json:"age" validate:"min=18,max=150"`
+	Plan    string     `json:"plan" validate:"in=free|pro"`
+	Address address    `json:"address"`
+	Born    *time.Time `json:"born" validate:"after=1900-01-01T00:00:00Z"`
+	Ignored string     `validate:"-"`
+}
+
+type shipping struct {
+	Zip string `json:"zip" validate:"regex=^\\d{5}$"`
+}
+
+type tree struct {
+	Name     string `json:"name"`
+	Parent   *tree  `json:"parent"`
+	Children []tree `json:"children"`
+}
+
+type node struct {
+	Name string `json:"name" validate:"required"`
+	Next *node  `json:"next"`
+}
+
+type chain struct {
+	*chain
+	Name string `json:"name" validate:"required"`
+}
+
+type order struct {
+	Shipping shipping `json:"shipping"`
+	Count    int      `json:"count" validate:"max=10"`
+}
+
+func TestStruct(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("RuleBookFor", func() {
+		g.It("Should create a rule for every recognized field keyed by json tag", func() {
+			rules := RuleBookFor(&userParams{}, true)
+			for _, key := range []string{"created_at", "first_name", "email", "age", "score", "admin", "tags", "address", "Nickname"} {
+				g.Assert(rules[key] != nil).IsTrue()
+			}
+			g.Assert(len(rules)).Equal(9)
+		})
+		g.It("Should map Go kinds to rule types", func() {
+			rules := RuleBookFor(userParams{}, true)
+			types := map[string]int{
+				"created_at": Time,
+				"first_name": String,
+				"age":        Int,
+				"score":      Float,
+				"admin":      Bool,
+				"tags":       Slice,
+				"Nickname":   String,
+			}
+			for key, expected := range types {
+				rule := rules[key].(interface{ Build() Rule }).Build()
+				g.Assert(rule.Type).Equal(expected)
+				g.Assert(rule.Required).IsTrue()
+			}
+		})
+		g.It("Should nest RuleBooks for nested structs", func() {
+			rules := RuleBookFor(&userParams{}, false)
+			address := rules["address"].(RuleBook)
+			g.Assert(len(address)).Equal(2)
+			g.Assert(address["city"].(interface{ Build() Rule }).Build().Required).IsFalse()
+		})
+		g.It("Should not recurse into a struct that refers back to itself", func() {
+			rules := RuleBookFor(&tree{}, false)
+			g.Assert(len(rules)).Equal(2)
+			g.Assert(rules["parent"] == nil).IsTrue()
+			children := rules["children"].(interface{ Build() Rule }).Build()
+			g.Assert(children.Type).Equal(Slice)
+			g.Assert(children.Element == nil).IsTrue()
+		})
+		g.It("Should not flatten a struct that embeds itself", func() {
+			rules := RuleBookFor(&chain{}, false)
+			g.Assert(len(rules)).Equal(1)
+			g.Assert(rules["name"] != nil).IsTrue()
+		})
+		g.It("Should return a RuleBook that can be adjusted and used", func() {
+			rules := RuleBookFor(&address{}, true)
+			rules["zip"] = RB.Required().Regex("^\\d{5}$")
+			_, errors := Validate(map[string]interface{}{
+				"city": "Boston",
+				"zip":  "0211",
+			}).With(rules)
+			g.Assert(len(errors)).Equal(1)
+			g.Assert(errors["zip"] != nil).IsTrue()
+		})
+	})
+
+	g.Describe("ValidateStruct", func() {
+		born := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+		valid := func() signup {
+			return signup{Name: "ann", Email: "ann@example.com", Age: 30, Plan: "pro", Born: &born}
+		}
+
+		g.It("Should pass a struct that satisfies its tags", func() {
+			s := valid()
+			g.Assert(len(ValidateStruct(&s))).Equal(0)
+		})
+		g.It("Should report every failing tag under its json key", func() {
+			tooEarly := time.Date(1850, 1, 1, 0, 0, 0, 0, time.UTC)
+			errors := ValidateStruct(signup{Name: "Ann1", Email: "nope", Age: 12, Plan: "gold", Born: &tooEarly})
+			for _, key := range []string{"name", "email", "age", "plan", "born"} {
+				g.Assert(len(errors[key])).Equal(1)
+			}
+		})
+		g.It("Should treat only nil pointers as missing", func() {
+			errors := ValidateStruct(signup{})
+			g.Assert(len(errors)).Equal(4)
+			for _, key := range []string{"name", "email", "age", "plan"} {
+				g.Assert(len(errors[key])).Equal(1)
+			}
+		})
+		g.It("Should validate a zero value against min and max", func() {
+			type reading struct {
+				Temp int `json:"temp" validate:"max=-5"`
+			}
+			g.Assert(len(ValidateStruct(reading{})["temp"])).Equal(1)
+			g.Assert(len(ValidateStruct(reading{Temp: -10}))).Equal(0)
+		})
+		g.It("Should bound the number of items of a slice with min and max", func() {
+			type post struct {
+				Tags []string `json:"tags" validate:"min=1,max=2"`
+			}
+			g.Assert(len(ValidateStruct(post{Tags: []string{}})["tags"])).Equal(1)
+			g.Assert(len(ValidateStruct(post{Tags: []string{"a", "b", "c"}})["tags"])).Equal(1)
+			g.Assert(len(ValidateStruct(post{Tags: []string{"a"}}))).Equal(0)
+		})
+		g.It("Should panic on min or max for a string", func() {
+			type bad struct {
+				X string `validate:"min=1"`
+			}
+			defer func() {
+				g.Assert(recover() != nil).IsTrue()
+			}()
+			ValidateStruct(bad{X: "a"})
+		})
+		g.It("Should validate nested structs under dotted paths", func() {
+			errors := ValidateStruct(order{Shipping: shipping{Zip: "abc"}, Count: 11})
+			g.Assert(len(errors["shipping.zip"])).Equal(1)
+			g.Assert(len(errors["count"])).Equal(1)
+		})
+		g.It("Should not recurse into a struct that refers back to itself", func() {
+			errors := ValidateStruct(&node{})
+			g.Assert(len(errors["name"])).Equal(1)
+			errors = ValidateStruct(&node{Name: "a", Next: &node{}})
+			g.Assert(len(errors)).Equal(0)
+		})
+		g.It("Should not flatten a struct that embeds itself", func() {
+			errors := ValidateStruct(chain{})
+			g.Assert(len(errors)).Equal(1)
+			g.Assert(len(errors["name"])).Equal(1)
+			g.Assert(len(ValidateStruct(chain{chain: &chain{}, Name: "a"}))).Equal(0)
+		})
+		g.It("Should panic on an unknown option", func() {
+			type bad struct {
+				X int `validate:"sometimes"`
+			}
+			defer func() {
+				g.Assert(recover() != nil).IsTrue()
+			}()
+			ValidateStruct(bad{X: 1})
+		})
+	})
+}
@@ -0,0 +1,229 @@
+package validate
+
+import (
+	"github.com/lann/builder"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TagName is the struct tag read by ValidateStruct(...)
+const TagName = "validate"
+
+// RuleBooks compiled from struct tags, keyed by struct type
+var tagCache sync.Map
+
+// ValidateStruct validates a struct against the rules declared in its
+// `validate` tags, e.g.
+//
+//	Age int `json:"age" validate:"required,min=1,max=150"`
+//
+// Recognized options are required, min=, max=, email, in=a|b|c, before= and
+// after= (RFC 3339) and regex=. min= and max= bound numbers, or the number of
+// items of a slice. Since a regex may contain commas it must be the last
+// option. Errors are keyed the same way RuleBookFor(...) keys rules.
+//
+// Only nil pointers count as missing; zero values are validated like any
+// other. The exception is a required field that isn't a pointer, which must
+// be non-zero.
+func ValidateStruct(v interface{}) map[string][]error {
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		panic("ValidateStruct(...) expects a struct. Got: " + reflect.TypeOf(v).String())
+	}
+
+	book := tagRuleBookFor(val.Type(), map[reflect.Type]bool{})
+	_, errors := Map(structValues(val, book), book)
+	return errors
+}
+
+// tagRuleBookFor returns the RuleBook for a struct type, compiling it from
+// the struct's tags the first time the type is seen. A field whose struct
+// type is still being compiled (e.g. Next *Node in Node) isn't validated.
+func tagRuleBookFor(t reflect.Type, compiling map[reflect.Type]bool) RuleBook {
+	if book, ok := tagCache.Load(t); ok {
+		return book.(RuleBook)
+	}
+	compiling[t] = true
+	defer delete(compiling, t)
+
+	book := RuleBook{}
+	for _, field := range fieldsOf(t) {
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		tag, hasTag := field.Tag.Lookup(TagName)
+		if tag == "-" {
+			continue
+		}
+		if ft.Kind() == reflect.Struct && ft != timeType {
+			if compiling[ft] {
+				Log.Debug("Not validating %v.%v: %v refers back to itself", t, field.Name, ft)
+				continue
+			}
+			if nested := tagRuleBookFor(ft, compiling); len(nested) > 0 {
+				book[field.key] = nested
+			}
+			continue
+		}
+		if !hasTag {
+			continue
+		}
+
+		rule, ok := ruleFor(field.Type, false, map[reflect.Type]bool{})
+		if !ok {
+			panic("Can't validate " + t.String() + "." + field.Name + ": unrecognized type " + field.Type.String())
+		}
+		book[field.key] = parseTag(rule.(ruleBuilder), tag, t.String()+"."+field.Name)
+	}
+
+	Log.Debug("Compiled RuleBook for %v: %v", t, book)
+	if len(compiling) == 1 {
+		// nested types are only cached as part of the outermost one, since
+		// which fields get skipped depends on where compiling started
+		tagCache.Store(t, book)
+	}
+	return book
+}
+
+// parseTag applies the options of a `validate` tag to a rule
+func parseTag(rb ruleBuilder, tag string, field string) ruleBuilder {
+	for tag != "" {
+		var option string
+		if strings.HasPrefix(tag, "regex=") {
+			option, tag = tag, ""
+		} else if i := strings.Index(tag, ","); i >= 0 {
+			option, tag = tag[:i], tag[i+1:]
+		} else {
+			option, tag = tag, ""
+		}
+
+		name, arg := option, ""
+		if i := strings.Index(option, "="); i >= 0 {
+			name, arg = option[:i], option[i+1:]
+		}
+
+		switch strings.TrimSpace(name) {
+		case "":
+		case "required":
+			rb = rb.Required()
+		case "email":
+			rb = rb.Email()
+		case "regex":
+			rb = rb.Regex(arg)
+		case "in":
+			rb = rb.In(strings.Split(arg, "|"))
+		case "min", "max":
+			rb = parseTagBound(rb, strings.TrimSpace(name), arg, field)
+		case "before":
+			rb = rb.Before(parseTagTime(arg, field))
+		case "after":
+			rb = rb.After(parseTagTime(arg, field))
+		default:
+			panic("Unknown option '" + name + "' in validate tag of " + field)
+		}
+	}
+
+	return rb
+}
+
+/* * * * * * * * * * * * *
+  Helper Functions
+* * * * * * * * * * * * */
+
+// parseTagBound applies min= or max= to a number, or to the length of a slice
+func parseTagBound(rb ruleBuilder, name string, arg string, field string) ruleBuilder {
+	switch rb.Build().Type {
+	case Int, Uint, Float:
+		// set directly so the field's own type is kept
+		bound, didSet := "Min", "DidSetMin"
+		if name == "max" {
+			bound, didSet = "Max", "DidSetMax"
+		}
+		rb = builder.Set(rb, bound, parseTagFloat(arg, field)).(ruleBuilder)
+		return builder.Set(rb, didSet, true).(ruleBuilder)
+	case Slice:
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			panic("Bad item count '" + arg + "' in validate tag of " + field)
+		}
+		if name == "max" {
+			return rb.MaxItems(n)
+		}
+		return rb.MinItems(n)
+	}
+
+	panic("Option '" + name + "' in validate tag of " + field + " only applies to numbers and slices")
+}
+
+func parseTagFloat(arg string, field string) float64 {
+	f, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		panic("Bad number '" + arg + "' in validate tag of " + field)
+	}
+	return f
+}
+
+func parseTagTime(arg string, field string) time.Time {
+	t, err := time.Parse(time.RFC3339, arg)
+	if err != nil {
+		panic("Bad time '" + arg + "' in validate tag of " + field)
+	}
+	return t
+}
+
+// structValues turns a struct into the map Map(...) expects, keyed by
+// fieldsOf(...) and with nested structs as nested maps. Nil pointers are left
+// out, as are zero values of fields that aren't pointers but are required by
+// the book, so they're reported as missing.
+func structValues(val reflect.Value, book RuleBook) map[string]interface{} {
+	given := make(map[string]interface{})
+	for _, field := range fieldsOf(val.Type()) {
+		fv, ok := fieldByIndex(val, field.index)
+		if !ok {
+			continue
+		}
+		for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Ptr {
+			continue
+		}
+
+		if fv.Kind() == reflect.Struct && fv.Type() != timeType {
+			nested, _ := book[field.key].(RuleBook)
+			given[field.key] = structValues(fv, nested)
+		} else if !fv.IsZero() || field.Type.Kind() == reflect.Ptr || !isRequired(book[field.key]) {
+			given[field.key] = fv.Interface()
+		}
+	}
+
+	return given
+}
+
+func isRequired(rule interface{}) bool {
+	rb, ok := rule.(ruleBuilder)
+	return ok && rb.Build().Required
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex without the panic on a nil
+// embedded pointer
+func fieldByIndex(val reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && val.Kind() == reflect.Ptr {
+			if val.IsNil() {
+				return reflect.Value{}, false
+			}
+			val = val.Elem()
+		}
+		val = val.Field(x)
+	}
+	return val, true
+}
@@ -0,0 +1,133 @@
+package validate
+
+import (
+	"math"
+	"math/big"
+	"time"
+)
+
+// Layouts a Time rule parses strings with unless given its own
+var DefaultLayouts = []string{time.RFC3339}
+
+// Layouts used by Date() and TimeOfDay(). A time of day parses to that time
+// on January 1st of year 0.
+var DateLayouts = []string{"2006-01-02"}
+var TimeOfDayLayouts = []string{"15:04:05", "15:04"}
+
+// Epoch timestamps must land within these years (inclusive), so that e.g.
+// milliseconds mistaken for seconds are refused rather than read as a date
+// tens of thousands of years out
+var MinEpochYear = 1900
+var MaxEpochYear = 2200
+
+var epochUnits = map[time.Duration]string{
+	time.Second:      "seconds",
+	time.Millisecond: "milliseconds",
+	time.Microsecond: "microseconds",
+	time.Nanosecond:  "nanoseconds",
+}
+
+// Clock tells the time relative rules (Past(), MinAge(), ...) are checked
+// against. Swap in a fixed one with Validate(...).Clock(...) for tests.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+var DefaultClock Clock = systemClock{}
+
+// coerceTime accepts a time.Time (or a non-nil *time.Time) as is and parses
+// a string with each of the rule's layouts in turn. With an Epoch unit,
+// numbers and numeric strings are read as Unix timestamps.
+func (rule *Rule) coerceTime(input interface{}) (interface{}, error) {
+	if rule.Epoch != 0 {
+		if val, ok := toRat(input); ok {
+			return rule.fromEpoch(val, input)
+		}
+		if str, ok := input.(string); ok && len(str) > MaxDecimalLength {
+			return input, ConversionError(input, typeName(rule.Type))
+		}
+	}
+
+	switch val := input.(type) {
+	case time.Time:
+		return val, nil
+	case *time.Time:
+		if val != nil {
+			return *val, nil
+		}
+	case string:
+		layouts := rule.layouts()
+		for _, layout := range layouts {
+			if t, err := time.Parse(layout, val); err == nil {
+				return t, nil
+			}
+		}
+		return input, NewError(CodeLayout, val, map[string]interface{}{"layouts": layouts},
+			"[%v] doesn't match any of the layouts %v", val, layouts)
+	}
+
+	return input, ConversionError(input, typeName(rule.Type))
+}
+
+func (rule *Rule) evalRelative(val time.Time, now time.Time) []error {
+	var errors []error
+
+	if rule.Past && !val.Before(now) {
+		errors = append(errors, NewError(CodePast, val, nil, "[%v] is not before %v", val, now))
+	}
+	if rule.Future && !val.After(now) {
+		errors = append(errors, NewError(CodeFuture, val, nil, "[%v] is not after %v", val, now))
+	}
+	if rule.WithinLast > 0 && (val.Before(now.Add(-rule.WithinLast)) || val.After(now)) {
+		errors = append(errors, NewError(CodeWithinLast, val, map[string]interface{}{"duration": rule.WithinLast},
+			"[%v] is not within %v before %v", val, rule.WithinLast, now))
+	}
+	if rule.WithinNext > 0 && (val.Before(now) || val.After(now.Add(rule.WithinNext))) {
+		errors = append(errors, NewError(CodeWithinNext, val, map[string]interface{}{"duration": rule.WithinNext},
+			"[%v] is not within %v after %v", val, rule.WithinNext, now))
+	}
+	if rule.MinAge > 0 && val.After(now.AddDate(-rule.MinAge, 0, 0)) {
+		errors = append(errors, NewError(CodeMinAge, val, map[string]interface{}{"years": rule.MinAge},
+			"[%v] is less than %v years before %v", val, rule.MinAge, now))
+	}
+
+	return errors
+}
+
+// fromEpoch converts a timestamp exactly, refusing any outside the years
+// MinEpochYear to MaxEpochYear
+func (rule *Rule) fromEpoch(val *big.Rat, input interface{}) (interface{}, error) {
+	nanos := new(big.Rat).Mul(val, new(big.Rat).SetInt64(int64(rule.Epoch)))
+	min := time.Date(MinEpochYear, 1, 1, 0, 0, 0, 0, time.UTC)
+	max := time.Date(MaxEpochYear+1, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	secs := new(big.Int).Div(nanos.Num(), nanos.Denom())
+	secs.Div(secs, big.NewInt(int64(time.Second)))
+	if !secs.IsInt64() || secs.Int64() < min.Unix() || secs.Int64() >= max.Unix() {
+		return input, NewError(CodeEpoch, input, map[string]interface{}{
+			"unit": epochUnits[rule.Epoch], "min_year": MinEpochYear, "max_year": MaxEpochYear},
+			"[%v] %v isn't between %v and %v", input, epochUnits[rule.Epoch], MinEpochYear, MaxEpochYear)
+	}
+
+	// nanoseconds since the (floored) second, rounding off anything finer
+	rest := new(big.Rat).Sub(nanos, new(big.Rat).SetInt(new(big.Int).Mul(secs, big.NewInt(int64(time.Second)))))
+	nsec, _ := rest.Float64()
+	return time.Unix(secs.Int64(), int64(math.Round(nsec))).UTC(), nil
+}
+
+/* * * * * * * * * * * * *
+  Helper Functions
+* * * * * * * * * * * * */
+
+func (rule *Rule) layouts() []string {
+	if len(rule.Layouts) > 0 {
+		return rule.Layouts
+	}
+	return DefaultLayouts
+}
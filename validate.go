@@ -1,9 +1,12 @@
 package validate
 
 import (
+	"fmt"
 	"github.com/op/go-logging"
 	"net/http"
 	"reflect"
+	"sort"
+	"time"
 )
 
 type RuleBook map[string]interface{}
@@ -12,17 +15,85 @@ var Log = logging.MustGetLogger("validate")
 
 type ValidationData struct {
 	data interface{}
+
+	// request limits
+	maxBodySize int64
+	maxDepth    int
+
+	// messages
+	locale  string
+	catalog Catalog
+
+	// unknown keys
+	strict      bool
+	passthrough bool
+
+	// what Past(), Future(), ... are relative to
+	clock Clock
+}
+
+// Validate wraps either a map[string]interface{} or an *http.Request for
+// validation against a RuleBook
+func Validate(data interface{}) *ValidationData {
+	return &ValidationData{
+		data:        data,
+		maxBodySize: DefaultMaxBodySize,
+		maxDepth:    DefaultMaxDepth,
+		locale:      DefaultLocale,
+		catalog:     DefaultCatalog,
+		clock:       DefaultClock,
+	}
+}
+
+// MaxBodySize limits how many bytes of a request body will be read
+func (v *ValidationData) MaxBodySize(size int64) *ValidationData {
+	v.maxBodySize = size
+	return v
+}
+
+// MaxDepth limits how deeply objects and arrays may nest in a JSON body
+func (v *ValidationData) MaxDepth(depth int) *ValidationData {
+	v.maxDepth = depth
+	return v
+}
+
+// Locale picks the language error messages are rendered in
+func (v *ValidationData) Locale(locale string) *ValidationData {
+	v.locale = locale
+	return v
+}
+
+// Catalog replaces the message templates error messages are rendered from
+func (v *ValidationData) Catalog(catalog Catalog) *ValidationData {
+	v.catalog = catalog
+	return v
+}
+
+// Strict reports every input key that isn't in the RuleBook as an error,
+// suggesting the closest known key
+func (v *ValidationData) Strict() *ValidationData {
+	v.strict, v.passthrough = true, false
+	return v
+}
+
+// Passthrough copies input keys that aren't in the RuleBook into params
+// untouched; by default they're dropped
+func (v *ValidationData) Passthrough() *ValidationData {
+	v.strict, v.passthrough = false, true
+	return v
 }
 
-func Validate(data map[string]interface{}) *ValidationData {
-	return &ValidationData{data: data}
+// Clock sets what relative time rules like Past() are checked against
+func (v *ValidationData) Clock(clock Clock) *ValidationData {
+	v.clock = clock
+	return v
 }
 
 func (v *ValidationData) With(rules RuleBook) (map[string]interface{}, map[string][]error) {
 	if _, ok := v.data.(*http.Request); ok {
-		return Request(v.data.(*http.Request), rules)
+		return v.request(v.data.(*http.Request), rules)
 	} else {
-		return Map(v.data.(map[string]interface{}), rules)
+		return v.validateMap(v.data.(map[string]interface{}), rules)
 	}
 }
 
@@ -37,29 +108,414 @@ func sameType(vals ...interface{}) bool {
 	return true
 }
 
-func Request(given *http.Request, expected RuleBook) (map[string]interface{}, map[string][]error) {
-	// TODO
-	return nil, nil
+func Map(given map[string]interface{}, expected RuleBook) (map[string]interface{}, map[string][]error) {
+	return Validate(given).With(expected)
 }
 
-func Map(given map[string]interface{}, expected RuleBook) (map[string]interface{}, map[string][]error) {
+// validation holds the state of a single With(...) call
+type validation struct {
+	*ValidationData
+	doc    *Document
+	errors map[string][]error
+
+	// read from the clock once so every rule sees the same instant
+	now time.Time
+
+	// params validated so far at the current level of the RuleBook and the
+	// raw input of that level
+	siblings map[string]interface{}
+	inputs   map[string]interface{}
+}
+
+func (v *ValidationData) session(doc *Document) *validation {
+	return &validation{ValidationData: v, doc: doc, errors: make(map[string][]error), now: v.clock.Now()}
+}
+
+func (v *ValidationData) validateMap(given map[string]interface{}, expected RuleBook) (map[string]interface{}, map[string][]error) {
+	return v.validateDocument(&Document{Data: given}, expected)
+}
+
+func (v *ValidationData) validateDocument(doc *Document, expected RuleBook) (map[string]interface{}, map[string][]error) {
+	s := v.session(doc)
+	doc.rules, doc.settings, doc.now = expected, v, s.now
+	params := s.book(doc.Data, expected, "")
+	return params, s.errors
+}
+
+// book validates given against a (possibly nested) RuleBook. Errors are
+// recorded under their full dotted path, e.g. "date.start".
+//
+// A rule reads its Key (or the RuleBook key) and then its Aliases from
+// given, taking the first one present. A key missing from given is skipped
+// unless its rule is Required or has a Default. A missing nested RuleBook is
+// checked against an empty map so its required keys are still reported.
+// Rules whose Conditions don't hold are skipped. Rules comparing against
+// other fields run once those fields are in params.
+// Keys of given that aren't in the RuleBook are dropped, reported or copied
+// as is depending on Strict() / Passthrough().
+func (s *validation) book(given map[string]interface{}, expected RuleBook, path string) map[string]interface{} {
 	params := make(map[string]interface{})
-	paramErrors := make(map[string][]error)
+	outerSiblings, outerInputs := s.siblings, s.inputs
+	s.siblings, s.inputs = params, inputsFor(given, expected)
+	defer func() { s.siblings, s.inputs = outerSiblings, outerInputs }()
 
+	var deferred []string
 	for k, v := range expected {
-		builder, ok := v.(ruleBuilder)
-		if ok {
-			rule := builder.Build()
-			input, errors := rule.Process(given[k])
-			if errors != nil {
-				paramErrors[k] = errors
+		key := joinPath(path, k)
+		switch v.(type) {
+		case ruleBuilder:
+			if len(v.(ruleBuilder).Build().Fields) > 0 {
+				deferred = append(deferred, k)
+				continue
+			}
+			s.field(params, given, k, v.(ruleBuilder), key)
+		case RuleBook:
+			input, present := given[k]
+			if !present {
+				s.book(map[string]interface{}{}, v.(RuleBook), key)
+				continue
+			}
+			nested, ok := toMap(input)
+			if !ok {
+				s.fail(nil, key, ConversionError(input, "map"))
+				continue
+			}
+			params[k] = s.book(nested, v.(RuleBook), key)
+		default:
+			Log.Warning("Ignoring %v: don't know how to apply %v", key, reflect.TypeOf(v))
+		}
+	}
+
+	// rules comparing against other fields go last, each after the fields
+	// it depends on unless they depend on each other in a cycle
+	sort.Strings(deferred)
+	for len(deferred) > 0 {
+		var waiting []string
+		for _, k := range deferred {
+			if !dependsOnAny(expected[k].(ruleBuilder), deferred, k) {
+				s.field(params, given, k, expected[k].(ruleBuilder), joinPath(path, k))
 			} else {
+				waiting = append(waiting, k)
+			}
+		}
+		if len(waiting) == len(deferred) {
+			for _, k := range waiting {
+				s.field(params, given, k, expected[k].(ruleBuilder), joinPath(path, k))
+			}
+			break
+		}
+		deferred = waiting
+	}
+
+	if s.strict || s.passthrough {
+		known := inputKeys(expected)
+		for k, input := range given {
+			// a RuleBook key is never passed through, even when its rule
+			// reads another key, since it names the validated value
+			if _, inBook := expected[k]; known[k] || inBook {
+				continue
+			}
+			if s.passthrough {
 				params[k] = input
+			} else {
+				s.fail(nil, joinPath(path, k), unknownError(k, known))
+			}
+		}
+	}
+
+	return params
+}
+
+// field validates the input for a single rule of a RuleBook into params
+func (s *validation) field(params map[string]interface{}, given map[string]interface{}, k string, rb ruleBuilder, key string) {
+	rule := rb.Build()
+	for _, condition := range rule.Conditions {
+		if !condition(s.inputs) {
+			return
+		}
+	}
+
+	input, present := lookup(given, rule.names(k))
+	if !present {
+		if rule.Required {
+			s.fail(&rule, key, NewError(CodeRequired, nil, nil, "Missing required value"))
+		} else if rule.Default != nil {
+			// checked when built, except for Checks and other fields
+			if output, ok := s.process(&rule, rule.Default, key); ok {
+				params[k] = output
 			}
 		}
+		return
+	}
+	if input, ok := s.process(&rule, input, key); ok {
+		params[k] = input
+	}
+}
+
+// process runs a single input through a rule's pipeline, recording its
+// errors (and those of its elements) under path:
+//
+//  1. Prepares transform the raw input before anything else. They run even
+//     when the input is nil, so a Prepare may substitute a value for null.
+//  2. A nil input is only accepted by a Nullable rule and is returned as
+//     is; Customs and Alters are skipped for it.
+//  3. The input is coerced to the rule's type and the built-in checks run,
+//     followed by the element rule for every item of a slice.
+//  4. Customs, Checks and then comparisons with other fields run on the
+//     coerced value once the built-in checks pass. Each Custom returning
+//     false, each Check returning an error and each failed comparison is
+//     reported.
+//  5. Alters transform the value that ends up in params, in order.
+//
+// The output is returned along with whether it passed.
+func (s *validation) process(rule *Rule, input interface{}, path string) (interface{}, bool) {
+	for _, prepare := range rule.Prepares {
+		input = prepare(input)
+	}
+
+	if input == nil {
+		if rule.Nullable {
+			return nil, true
+		}
+		s.fail(rule, path, NewError(CodeNull, nil, nil, "Null value not allowed"))
+		return nil, false
+	}
+
+	output, errors := rule.check(input, s.now)
+	if len(errors) > 0 {
+		for _, err := range errors {
+			s.fail(rule, path, err)
+		}
+		return output, false
+	}
+	if rule.Type == Slice {
+		var ok bool
+		if output, ok = s.elements(rule, output.([]interface{}), path); !ok {
+			return output, false
+		}
 	}
 
-	return params, paramErrors
+	allOk := true
+	for i, custom := range rule.Customs {
+		if !custom(output) {
+			s.fail(rule, path, NewError(CodeCustom, output, map[string]interface{}{"index": i},
+				"[%v] failed custom validation #%v", output, i+1))
+			allOk = false
+		}
+	}
+	for _, check := range rule.Checks {
+		if err := check(output, path, s.doc); err != nil {
+			s.fail(rule, path, err)
+			allOk = false
+		}
+	}
+	for _, cmp := range rule.Fields {
+		if err := s.compare(cmp, output); err != nil {
+			s.fail(rule, path, err)
+			allOk = false
+		}
+	}
+	if !allOk {
+		return output, false
+	}
+
+	for _, alter := range rule.Alters {
+		output = alter(output)
+	}
+	return output, true
+}
+
+// elements processes every item of a slice with the rule's Element rule
+func (s *validation) elements(rule *Rule, vals []interface{}, path string) ([]interface{}, bool) {
+	allOk := true
+	coerced := make([]interface{}, len(vals))
+	for i, val := range vals {
+		if rule.Element == nil {
+			coerced[i] = val
+		} else if elem, ok := s.process(rule.Element, val, fmt.Sprintf("%v[%v]", path, i)); ok {
+			coerced[i] = elem
+		} else {
+			allOk = false
+		}
+	}
+	if allOk && rule.Unique {
+		if ok, err := rule.evalUnique(coerced); !ok {
+			s.fail(rule, path, err)
+			allOk = false
+		}
+	}
+
+	return coerced, allOk
+}
+
+// fail records an error with its message rendered for the session's locale
+func (s *validation) fail(rule *Rule, path string, err error) {
+	ve := addError(s.errors, path, err)
+	ve.Message = s.message(rule, ve)
+}
+
+// flatten collects every error in path order
+func (s *validation) flatten() []error {
+	var paths []string
+	for path := range s.errors {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var errors []error
+	for _, path := range paths {
+		errors = append(errors, s.errors[path]...)
+	}
+	return errors
+}
+
+/* * * * * * * * * * * * *
+  Helper Functions
+* * * * * * * * * * * * */
+
+// names lists the input keys a rule reads, in order of preference
+func (rule *Rule) names(key string) []string {
+	if rule.Key != "" {
+		key = rule.Key
+	}
+	return append([]string{key}, rule.Aliases...)
+}
+
+// lookup returns the value of the first of names present in given
+func lookup(given map[string]interface{}, names []string) (interface{}, bool) {
+	for _, name := range names {
+		if input, ok := given[name]; ok {
+			return input, true
+		}
+	}
+	return nil, false
+}
+
+// inputsFor keys the raw input of a RuleBook level by RuleBook key, so
+// predicates needn't care which alias was sent. Other keys are kept as is.
+func inputsFor(given map[string]interface{}, expected RuleBook) map[string]interface{} {
+	inputs := make(map[string]interface{}, len(given))
+	for k, input := range given {
+		inputs[k] = input
+	}
+	for k, v := range expected {
+		if rb, ok := v.(ruleBuilder); ok {
+			rule := rb.Build()
+			if input, present := lookup(given, rule.names(k)); present {
+				inputs[k] = input
+			} else {
+				delete(inputs, k) // sent under a key the rule doesn't read
+			}
+		}
+	}
+	return inputs
+}
+
+// inputKeys collects every input key a RuleBook accepts at its level
+func inputKeys(expected RuleBook) map[string]bool {
+	keys := make(map[string]bool)
+	for k, v := range expected {
+		if rb, ok := v.(ruleBuilder); ok {
+			rule := rb.Build()
+			for _, name := range rule.names(k) {
+				keys[name] = true
+			}
+		} else {
+			keys[k] = true
+		}
+	}
+	return keys
+}
+
+func unknownError(key string, known map[string]bool) error {
+	params := map[string]interface{}{}
+	suggestion, ok := suggest(key, known)
+	if !ok {
+		return NewError(CodeUnknown, nil, params, "Unknown field `%v`", key)
+	}
+
+	params["suggestion"] = suggestion
+	return NewError(CodeUnknown, nil, params, "Unknown field `%v`, did you mean `%v`?", key, suggestion)
+}
+
+// suggest finds the known key closest to key, if any is close enough to be
+// a likely typo
+func suggest(key string, known map[string]bool) (string, bool) {
+	best, bestDistance := "", -1
+	for candidate := range known {
+		distance := editDistance(key, candidate)
+		if bestDistance < 0 || distance < bestDistance || (distance == bestDistance && candidate < best) {
+			best, bestDistance = candidate, distance
+		}
+	}
+
+	limit := 2
+	if len(key) < 4 {
+		limit = 1
+	}
+	return best, bestDistance >= 0 && bestDistance <= limit
+}
+
+// editDistance is the Damerau-Levenshtein (optimal string alignment)
+// distance, so swapped letters like "emial" count as a single edit
+func editDistance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = minInt(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+func minInt(vals ...int) int {
+	m := vals[0]
+	for _, val := range vals[1:] {
+		if val < m {
+			m = val
+		}
+	}
+	return m
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// toMap accepts any map keyed by strings, e.g. map[string]time.Time
+func toMap(val interface{}) (map[string]interface{}, bool) {
+	if m, ok := val.(map[string]interface{}); ok {
+		return m, true
+	}
+
+	v := reflect.ValueOf(val)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+	m := make(map[string]interface{}, v.Len())
+	for _, k := range v.MapKeys() {
+		m[k.String()] = v.MapIndex(k).Interface()
+	}
+	return m, true
 }
 
 func SetLoggingLevel(level logging.Level) {
@@ -1,10 +1,11 @@
 package validate_test
 
 import (
-	_ "fmt"
+	"fmt"
 	. "github.com/franela/goblin"
 	. "github.com/joslinm/validate"
 	"testing"
+	"time"
 )
 
 func TestValidate(t *testing.T) {
@@ -35,5 +36,498 @@ func TestValidate(t *testing.T) {
 				g.Assert(errors["y"] != nil).IsTrue()
 			})
 		})
+
+		g.Describe("Nesting", func() {
+			g.It("Should validate nested RuleBooks and return nested params", func() {
+				params, errors := Validate(map[string]interface{}{
+					"date": map[string]interface{}{
+						"start": "4",
+						"end":   "hi!",
+					},
+				}).With(RuleBook{
+					"date": RuleBook{
+						"start": RB.Min(1),
+						"end":   RB.Regex("hi.*"),
+					},
+				})
+				g.Assert(len(errors)).Equal(0)
+				date := params["date"].(map[string]interface{})
+				g.Assert(date["start"]).Equal(float64(4))
+				g.Assert(date["end"]).Equal("hi!")
+			})
+			g.It("Should accept any map keyed by strings", func() {
+				params, errors := Validate(map[string]interface{}{
+					"date": map[string]time.Time{"start": time.Now()},
+				}).With(RuleBook{
+					"date": RuleBook{"start": RB.Time()},
+				})
+				g.Assert(len(errors)).Equal(0)
+				g.Assert(params["date"] != nil).IsTrue()
+			})
+			g.It("Should report nested errors under dotted paths", func() {
+				_, errors := Validate(map[string]interface{}{
+					"date": map[string]interface{}{"start": "0"},
+				}).With(RuleBook{
+					"date": RuleBook{"start": RB.Min(1)},
+				})
+				g.Assert(errors["date.start"] != nil).IsTrue()
+			})
+			g.It("Should error if a nested value isn't a map", func() {
+				_, errors := Validate(map[string]interface{}{
+					"date": "today",
+				}).With(RuleBook{
+					"date": RuleBook{"start": RB.Min(1)},
+				})
+				g.Assert(len(errors["date"])).Equal(1)
+			})
+		})
+
+		g.Describe("Slices", func() {
+			g.It("Should coerce every element", func() {
+				params, errors := Validate(map[string]interface{}{
+					"scores": []interface{}{"1", 2.5, "3"},
+				}).With(RuleBook{
+					"scores": RB.Each(RB.Number().Min(0)),
+				})
+				g.Assert(len(errors)).Equal(0)
+				g.Assert(params["scores"]).Equal([]interface{}{float64(1), 2.5, float64(3)})
+			})
+			g.It("Should report element errors by index", func() {
+				_, errors := Validate(map[string]interface{}{
+					"tags": []string{"a", "b", "c", "!"},
+				}).With(RuleBook{
+					"tags": RB.Each(RB.Regex("^\\w+$")),
+				})
+				g.Assert(len(errors)).Equal(1)
+				g.Assert(errors["tags[3]"] != nil).IsTrue()
+			})
+			g.It("Should enforce MinItems, MaxItems and Unique", func() {
+				rules := RuleBook{"ids": RB.Each(RB.Number()).MinItems(2).MaxItems(3).Unique()}
+
+				_, errors := Validate(map[string]interface{}{"ids": []interface{}{1}}).With(rules)
+				g.Assert(len(errors["ids"])).Equal(1)
+				_, errors = Validate(map[string]interface{}{"ids": []interface{}{1, 2, 3, 4}}).With(rules)
+				g.Assert(len(errors["ids"])).Equal(1)
+				_, errors = Validate(map[string]interface{}{"ids": []interface{}{"1", 1.0}}).With(rules)
+				g.Assert(len(errors["ids"])).Equal(1)
+				_, errors = Validate(map[string]interface{}{"ids": []interface{}{1, 2}}).With(rules)
+				g.Assert(len(errors)).Equal(0)
+			})
+			g.It("Should error if the input isn't a slice", func() {
+				_, errors := Validate(map[string]interface{}{
+					"tags": "a",
+				}).With(RuleBook{
+					"tags": RB.Each(RB.String()),
+				})
+				g.Assert(len(errors["tags"])).Equal(1)
+			})
+		})
+
+		g.Describe("Presence", func() {
+			rules := RuleBook{
+				"name":  RB.Required().String(),
+				"nick":  RB.String(),
+				"note":  RB.String().Nullable(),
+				"count": RB.Number(),
+			}
+
+			g.It("Should skip missing optional values", func() {
+				params, errors := Validate(map[string]interface{}{
+					"name": "ann",
+				}).With(rules)
+				g.Assert(len(errors)).Equal(0)
+				g.Assert(len(params)).Equal(1)
+			})
+			g.It("Should error on a missing required value", func() {
+				_, errors := Validate(map[string]interface{}{}).With(rules)
+				g.Assert(len(errors)).Equal(1)
+				g.Assert(len(errors["name"])).Equal(1)
+			})
+			g.It("Should only accept null for nullable rules", func() {
+				params, errors := Validate(map[string]interface{}{
+					"name": "ann",
+					"nick": nil,
+					"note": nil,
+				}).With(rules)
+				g.Assert(len(errors)).Equal(1)
+				g.Assert(len(errors["nick"])).Equal(1)
+				_, hasNote := params["note"]
+				g.Assert(hasNote).IsTrue()
+				g.Assert(params["note"] == nil).IsTrue()
+			})
+			g.It("Should validate zero values like any other", func() {
+				params, errors := Validate(map[string]interface{}{
+					"name":  "",
+					"count": 0,
+				}).With(rules)
+				g.Assert(len(errors)).Equal(0)
+				g.Assert(params["name"]).Equal("")
+				g.Assert(params["count"]).Equal(0)
+			})
+			g.It("Should report required keys of a missing nested RuleBook", func() {
+				_, errors := Validate(map[string]interface{}{}).With(RuleBook{
+					"date": RuleBook{
+						"start": RB.Required().Time(),
+						"end":   RB.Time(),
+					},
+				})
+				g.Assert(len(errors)).Equal(1)
+				g.Assert(len(errors["date.start"])).Equal(1)
+			})
+		})
+
+		g.Describe("Defaults", func() {
+			g.It("Should fill in missing optional values", func() {
+				params, errors := Validate(map[string]interface{}{
+					"sort": "desc",
+				}).With(RuleBook{
+					"limit":  RB.Max(100).Default(25),
+					"sort":   RB.In([]string{"asc", "desc"}).Default("asc"),
+					"active": RB.Default(true),
+					"tags":   RB.Each(RB.String()).Default([]string{"new"}),
+				})
+				g.Assert(len(errors)).Equal(0)
+				g.Assert(params["limit"]).Equal(25)
+				g.Assert(params["sort"]).Equal("desc")
+				g.Assert(params["active"]).Equal(true)
+				g.Assert(params["tags"]).Equal([]interface{}{"new"})
+			})
+			g.It("Should not fill in a missing required value", func() {
+				params, errors := Validate(map[string]interface{}{}).With(RuleBook{
+					"limit": RB.Required().Number().Default(25),
+				})
+				g.Assert(len(errors["limit"])).Equal(1)
+				g.Assert(params["limit"] == nil).IsTrue()
+			})
+			g.It("Should coerce the default with the rule as finally built", func() {
+				params, _ := Map(map[string]interface{}{}, RuleBook{
+					"n":    RB.Default(10).Int(),
+					"tags": RB.Default([]string{"1", "2"}).Each(RB.Int()),
+				})
+				g.Assert(params["n"]).Equal(int64(10))
+				g.Assert(params["tags"]).Equal([]interface{}{int64(1), int64(2)})
+			})
+			g.It("Should panic when built with a default that fails its rule", func() {
+				for _, build := range []func() interface{}{
+					func() interface{} { return RB.Default("x").Min(1) },
+					func() interface{} { return RB.Max(100).Default(500) },
+					func() interface{} { return RB.In([]string{"a"}).Default("zzz") },
+					func() interface{} { return RB.Each(RB.Int()).Default([]string{"x"}) },
+					func() interface{} { return RB.Default(300).Int().Bits(8) },
+				} {
+					func() {
+						defer func() {
+							g.Assert(recover() != nil).IsTrue()
+						}()
+						build()
+					}()
+				}
+			})
+			g.It("Should report a default failing a Check rather than panic", func() {
+				rules := RuleBook{
+					"n": RB.Default(5).Check(func(val interface{}, path string, doc *Document) error {
+						return fmt.Errorf("%v is taken", path)
+					}),
+				}
+				params, errors := Map(map[string]interface{}{}, rules)
+				g.Assert(len(errors["n"])).Equal(1)
+				g.Assert(params["n"] == nil).IsTrue()
+			})
+			g.It("Should panic when built with a default of the wrong type", func() {
+				defer func() {
+					g.Assert(recover() != nil).IsTrue()
+				}()
+				RB.Bool().Default("maybe")
+			})
+		})
+
+		g.Describe("Checks", func() {
+			endAfterStart := RB.Time().Check(func(val interface{}, path string, doc *Document) error {
+				start, ok := doc.Value("date.start")
+				if !ok {
+					return nil
+				}
+				if !val.(time.Time).After(start.(time.Time)) {
+					return fmt.Errorf("%v must be after date.start", path)
+				}
+				return nil
+			})
+			rules := RuleBook{
+				"date": RuleBook{
+					"start": RB.Time(),
+					"end":   endAfterStart,
+				},
+			}
+			now := time.Now()
+
+			g.It("Should give Checks the path and whole document", func() {
+				_, errors := Validate(map[string]interface{}{
+					"date": map[string]interface{}{"start": now, "end": now.Add(-time.Hour)},
+				}).With(rules)
+				g.Assert(len(errors)).Equal(1)
+				g.Assert(errors["date.end"][0].Error()).Equal("date.end must be after date.start")
+			})
+			g.It("Should give Checks the coerced values of other fields", func() {
+				_, errors := Validate(map[string]interface{}{
+					"date": map[string]interface{}{"start": "2024-03-01T10:00:00Z", "end": "2024-03-01T09:00:00Z"},
+				}).With(rules)
+				g.Assert(len(errors["date.end"])).Equal(1)
+				_, errors = Validate(map[string]interface{}{
+					"date": map[string]interface{}{"start": "2024-03-01T10:00:00Z", "end": "2024-03-01T11:00:00Z"},
+				}).With(rules)
+				g.Assert(len(errors)).Equal(0)
+				_, errors = Validate(map[string]interface{}{
+					"date": map[string]interface{}{"start": "not a time", "end": "2024-03-01T11:00:00Z"},
+				}).With(rules)
+				g.Assert(len(errors["date.start"])).Equal(1)
+				g.Assert(len(errors["date.end"])).Equal(0)
+			})
+			g.It("Should look up coerced values by key, default and index", func() {
+				var seen []interface{}
+				rules := RuleBook{
+					"n":    RB.Int().Key("num"),
+					"m":    RB.Default(7).Int(),
+					"tags": RB.Each(RB.Int()),
+					"x": RB.String().Check(func(val interface{}, path string, doc *Document) error {
+						for _, p := range []string{"n", "m", "tags[1]", "tags[5]", "nope"} {
+							v, _ := doc.Value(p)
+							seen = append(seen, v)
+						}
+						return nil
+					}),
+				}
+				Map(map[string]interface{}{"num": "3", "tags": []interface{}{"1", "2"}, "x": "y"}, rules)
+				g.Assert(seen).Equal([]interface{}{int64(3), int64(7), int64(2), nil, nil})
+			})
+			g.It("Should pass when every Check returns nil", func() {
+				_, errors := Validate(map[string]interface{}{
+					"date": map[string]interface{}{"start": now, "end": now.Add(time.Hour)},
+				}).With(rules)
+				g.Assert(len(errors)).Equal(0)
+			})
+			g.It("Should look up values in slices by index", func() {
+				doc := &Document{Data: map[string]interface{}{
+					"tags": []interface{}{"a", map[string]interface{}{"b": 2}},
+				}}
+				val, ok := doc.Get("tags[1].b")
+				g.Assert(ok).IsTrue()
+				g.Assert(val).Equal(2)
+				_, ok = doc.Get("tags[2]")
+				g.Assert(ok).IsFalse()
+			})
+		})
+
+		g.Describe("Unknown keys", func() {
+			input := map[string]interface{}{
+				"email":   "ann@example.com",
+				"emial":   "typo@example.com",
+				"zzz":     1,
+				"address": map[string]interface{}{"zp": "02111"},
+			}
+			rules := RuleBook{
+				"email":   RB.Email(),
+				"name":    RB.String(),
+				"address": RuleBook{"zip": RB.String()},
+			}
+
+			g.It("Should drop unknown keys by default", func() {
+				params, errors := Validate(input).With(rules)
+				g.Assert(len(errors)).Equal(0)
+				g.Assert(params["emial"] == nil).IsTrue()
+			})
+			g.It("Should report unknown keys in strict mode with suggestions", func() {
+				_, errors := Validate(input).Strict().With(rules)
+				g.Assert(len(errors)).Equal(3)
+				g.Assert(errors["emial"][0].Error()).Equal("unknown field `emial`, did you mean `email`?")
+				g.Assert(errors["zzz"][0].Error()).Equal("unknown field `zzz`")
+				g.Assert(errors["address.zp"][0].Error()).Equal("unknown field `address.zp`, did you mean `zip`?")
+			})
+			g.It("Should copy unknown keys in passthrough mode", func() {
+				params, errors := Validate(input).Passthrough().With(rules)
+				g.Assert(len(errors)).Equal(0)
+				g.Assert(params["emial"]).Equal("typo@example.com")
+				g.Assert(params["zzz"]).Equal(1)
+				g.Assert(params["address"].(map[string]interface{})["zp"]).Equal("02111")
+			})
+		})
+		g.Describe("Other fields", func() {
+			start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			rules := RuleBook{
+				"password":         RB.Required().String(),
+				"password_confirm": RB.Required().String().EqualsField("password"),
+				"min_price":        RB.Number(),
+				"max_price":        RB.Number().GreaterThanOrEqualField("min_price"),
+				"range": RuleBook{
+					"start": RB.Time(),
+					"end":   RB.AfterField("start"),
+				},
+			}
+
+			g.It("Should pass when the fields agree", func() {
+				_, errors := Map(map[string]interface{}{
+					"password":         "secret",
+					"password_confirm": "secret",
+					"min_price":        5,
+					"max_price":        5.0,
+					"range":            map[string]interface{}{"start": start, "end": start.Add(time.Hour)},
+				}, rules)
+				g.Assert(len(errors)).Equal(0)
+			})
+			g.It("Should report the dependent field", func() {
+				_, errors := Map(map[string]interface{}{
+					"password":         "secret",
+					"password_confirm": "secrte",
+					"min_price":        "10",
+					"max_price":        5,
+					"range":            map[string]interface{}{"start": start, "end": start},
+				}, rules)
+				g.Assert(len(errors)).Equal(3)
+				g.Assert(errors["password_confirm"][0].Error()).Equal("password_confirm must match password")
+				g.Assert(errors["max_price"][0].Error()).Equal("max_price must be at least min_price")
+				g.Assert(errors["range.end"][0].Error()).Equal("range.end must be after start")
+			})
+			g.It("Should skip the comparison when the other field is missing", func() {
+				_, errors := Map(map[string]interface{}{"max_price": 5}, RuleBook{
+					"min_price": RB.Number(),
+					"max_price": RB.Number().GreaterThanField("min_price"),
+				})
+				g.Assert(len(errors)).Equal(0)
+			})
+			g.It("Should order fields that depend on each other", func() {
+				_, errors := Map(map[string]interface{}{"a": 1, "b": 2, "c": 3}, RuleBook{
+					"a": RB.Number(),
+					"b": RB.Number().GreaterThanField("a"),
+					"c": RB.Number().GreaterThanField("b"),
+				})
+				g.Assert(len(errors)).Equal(0)
+				_, errors = Map(map[string]interface{}{"a": 1, "b": 2, "c": 2}, RuleBook{
+					"a": RB.Number(),
+					"b": RB.Number().GreaterThanField("a"),
+					"c": RB.Number().GreaterThanField("b"),
+				})
+				g.Assert(len(errors["c"])).Equal(1)
+			})
+		})
+
+		g.Describe("Conditions", func() {
+			rules := RuleBook{
+				"country": RB.Required().String(),
+				"state":   RB.String().Regex("^[A-Z]{2}$").RequiredIf("country", "US"),
+				"zip":     RB.String().RequiredUnless("country", "IE"),
+				"vat": When(func(siblings map[string]interface{}) bool {
+					return siblings["business"] == true
+				}, RB.Required().String()),
+				"business": RB.Bool(),
+			}
+
+			g.It("Should require fields whose condition holds", func() {
+				_, errors := Map(map[string]interface{}{"country": "US", "business": true}, rules)
+				g.Assert(len(errors)).Equal(3)
+				g.Assert(len(errors["state"])).Equal(1)
+				g.Assert(len(errors["zip"])).Equal(1)
+				g.Assert(len(errors["vat"])).Equal(1)
+			})
+			g.It("Should skip fields whose condition doesn't hold", func() {
+				params, errors := Map(map[string]interface{}{"country": "IE", "state": "dublin", "zip": 1}, rules)
+				g.Assert(len(errors)).Equal(0)
+				g.Assert(params["state"] == nil).IsTrue()
+				g.Assert(params["zip"] == nil).IsTrue()
+			})
+			g.It("Should compare request values by their string form", func() {
+				_, errors := Map(map[string]interface{}{"country": "CA", "n": "1"}, RuleBook{
+					"n":     RB.String(),
+					"extra": RB.String().RequiredIf("n", 1),
+				})
+				g.Assert(len(errors["extra"])).Equal(1)
+			})
+		})
+
+		g.Describe("Relative times", func() {
+			now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+			clock := fixedClock{now}
+			rules := RuleBook{
+				"born":     RB.Past().MinAge(18),
+				"seen":     RB.WithinLast(24 * time.Hour),
+				"starts":   RB.Future(),
+				"deadline": RB.WithinNext(7 * 24 * time.Hour),
+			}
+
+			g.It("Should check times against the clock at validation", func() {
+				_, errors := Validate(map[string]interface{}{
+					"born":     now.AddDate(-18, 0, 0),
+					"seen":     now.Add(-time.Hour),
+					"starts":   now.Add(time.Minute),
+					"deadline": now.AddDate(0, 0, 7),
+				}).Clock(clock).With(rules)
+				g.Assert(len(errors)).Equal(0)
+			})
+			g.It("Should report times on the wrong side of the clock", func() {
+				_, errors := Validate(map[string]interface{}{
+					"born":     now.AddDate(-18, 0, 1),
+					"seen":     now.Add(-25 * time.Hour),
+					"starts":   now,
+					"deadline": now.AddDate(0, 0, 8),
+				}).Clock(clock).With(rules)
+				g.Assert(len(errors)).Equal(4)
+				g.Assert(errors["born"][0].Error()).Equal("born must be at least 18 years ago")
+				g.Assert(errors["seen"][0].(*ValidationError).Code).Equal(CodeWithinLast)
+				g.Assert(errors["starts"][0].Error()).Equal("starts must be in the future")
+				g.Assert(errors["deadline"][0].(*ValidationError).Code).Equal(CodeWithinNext)
+			})
+			g.It("Should use the real time by default", func() {
+				_, errors := Map(map[string]interface{}{"starts": time.Now().Add(time.Hour)}, rules)
+				g.Assert(len(errors)).Equal(0)
+			})
+		})
+
+		g.Describe("Aliases", func() {
+			rules := RuleBook{
+				"user_id": RB.Required().String().Alias("userId"),
+				"name":    RB.String().Key("fullName"),
+			}
+
+			g.It("Should read an alias and emit under the RuleBook key", func() {
+				params, errors := Map(map[string]interface{}{"userId": "7", "fullName": "Ann"}, rules)
+				g.Assert(len(errors)).Equal(0)
+				g.Assert(params["user_id"]).Equal("7")
+				g.Assert(params["name"]).Equal("Ann")
+				g.Assert(params["userId"] == nil).IsTrue()
+			})
+			g.It("Should prefer the primary key over its aliases", func() {
+				params, _ := Map(map[string]interface{}{"user_id": "1", "userId": "2"}, rules)
+				g.Assert(params["user_id"]).Equal("1")
+			})
+			g.It("Should report errors under the RuleBook key", func() {
+				_, errors := Map(map[string]interface{}{"userId": true}, rules)
+				g.Assert(len(errors["user_id"])).Equal(1)
+			})
+			g.It("Should not read the RuleBook key when Key is set", func() {
+				params, _ := Map(map[string]interface{}{"user_id": "1", "name": "Ann"}, rules)
+				g.Assert(params["name"] == nil).IsTrue()
+			})
+			g.It("Should never pass through under a RuleBook key", func() {
+				rules := RuleBook{"user_id": RB.Int().Key("userId")}
+				params, errors := Validate(map[string]interface{}{"userId": "5", "user_id": "garbage"}).Passthrough().With(rules)
+				g.Assert(len(errors)).Equal(0)
+				g.Assert(params["user_id"]).Equal(int64(5))
+				params, _ = Validate(map[string]interface{}{"user_id": "garbage"}).Passthrough().With(rules)
+				g.Assert(params["user_id"] == nil).IsTrue()
+				_, errors = Validate(map[string]interface{}{"user_id": "5"}).Strict().With(rules)
+				g.Assert(len(errors)).Equal(0)
+			})
+			g.It("Should accept aliases in strict mode", func() {
+				_, errors := Validate(map[string]interface{}{"userId": "1", "fullNam": "Ann"}).Strict().With(rules)
+				g.Assert(len(errors)).Equal(1)
+				g.Assert(errors["fullNam"][0].Error()).Equal("unknown field `fullNam`, did you mean `fullName`?")
+			})
+		})
 	})
 }
+
+type fixedClock struct {
+	now time.Time
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.now
+}